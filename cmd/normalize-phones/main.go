@@ -0,0 +1,160 @@
+// Command normalize-phones rewrites the phone numbers stored before numbers
+// were parsed into E.164 form, so that old accounts, OTP request history and
+// phone rules match the numbers the service now looks up.
+//
+// It reads the same environment as the server. Without -apply it only
+// prints what it would change. Numbers it cannot parse and changes that
+// would collide with another row are left alone and reported; the command
+// then exits with status 1, and can be run again once they are resolved.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/phone"
+	"otp-auth-service/internal/repository"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "write the changes instead of only printing them")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", cfg.Database.Postgres.User, cfg.Database.Postgres.Password, cfg.Database.Postgres.Host, cfg.Database.Postgres.Port, cfg.Database.Postgres.DatabaseName)
+	db, err := gorm.Open(postgres.Open(dbURL), &gorm.Config{TranslateError: true})
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	if cfg.Database.Redis.Port == "" {
+		cfg.Database.Redis.Port = "6379"
+	}
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", cfg.Database.Redis.Host, cfg.Database.Redis.Port),
+		Password: cfg.Database.Redis.Password,
+		DB:       cfg.Database.Redis.Database,
+	})
+	if _, err := redisClient.Ping(context.Background()).Result(); err != nil {
+		log.Fatal(err)
+	}
+
+	n := &normalizer{parser: phone.NewParser(cfg.Phone.DefaultRegion)}
+
+	// Deleted accounts still hold their number under the unique constraint
+	var users []model.User
+	if err := db.Unscoped().Select("id", "phone_number").Order("id").Find(&users).Error; err != nil {
+		log.Fatal(err)
+	}
+	userChanges := n.users(users)
+
+	var requestNumbers []string
+	// Purged accounts leave their requests without a number
+	err = db.Model(&model.OTPRequest{}).Where("phone_number <> ''").Distinct().Pluck("phone_number", &requestNumbers).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+	requestChanges := n.numbers("otp request", requestNumbers)
+
+	// Ownership windows of exports and purges compare history numbers with
+	// OTP requests, so both must use the same form
+	var historyNumbers []string
+	err = db.Raw("SELECT old_phone_number FROM phone_number_history UNION SELECT new_phone_number FROM phone_number_history").
+		Scan(&historyNumbers).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+	historyChanges := n.numbers("phone number history", historyNumbers)
+
+	var rules []model.PhoneRule
+	if err := db.Order("id").Find(&rules).Error; err != nil {
+		log.Fatal(err)
+	}
+	ruleChanges := n.rules(rules)
+
+	for _, user := range users {
+		if number, ok := userChanges[user.ID]; ok {
+			fmt.Printf("user %d: %s -> %s\n", user.ID, user.PhoneNumber, number)
+		}
+	}
+	for _, from := range sortedKeys(requestChanges) {
+		fmt.Printf("otp requests: %s -> %s\n", from, requestChanges[from])
+	}
+	for _, from := range sortedKeys(historyChanges) {
+		fmt.Printf("phone number history: %s -> %s\n", from, historyChanges[from])
+	}
+	for _, rule := range rules {
+		if value, ok := ruleChanges[rule.ID]; ok {
+			fmt.Printf("phone rule %d: %s -> %s\n", rule.ID, rule.Value, value)
+		}
+	}
+
+	if *apply {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for id, number := range userChanges {
+				err := tx.Unscoped().Model(&model.User{}).Where("id = ?", id).
+					UpdateColumn("phone_number", number).Error
+				if err != nil {
+					return err
+				}
+			}
+			for from, to := range requestChanges {
+				err := tx.Model(&model.OTPRequest{}).Where("phone_number = ?", from).
+					UpdateColumn("phone_number", to).Error
+				if err != nil {
+					return err
+				}
+			}
+			for from, to := range historyChanges {
+				err := tx.Model(&model.PhoneNumberHistory{}).Where("old_phone_number = ?", from).
+					UpdateColumn("old_phone_number", to).Error
+				if err != nil {
+					return err
+				}
+				err = tx.Model(&model.PhoneNumberHistory{}).Where("new_phone_number = ?", from).
+					UpdateColumn("new_phone_number", to).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal("Failed to normalize phone numbers:", err)
+		}
+
+		// Rules go through the repository so the cached lists are dropped
+		phoneRuleRepo := repository.NewPhoneRuleRepository(redisClient, db)
+		for _, rule := range rules {
+			value, ok := ruleChanges[rule.ID]
+			if !ok {
+				continue
+			}
+			rule.Value = value
+			if err := phoneRuleRepo.Update(&rule); err != nil {
+				log.Fatalf("Failed to normalize phone rule %d: %v", rule.ID, err)
+			}
+		}
+	} else {
+		fmt.Println("dry run; pass -apply to write these changes")
+	}
+
+	for _, problem := range n.problems {
+		fmt.Fprintln(os.Stderr, "left unchanged:", problem)
+	}
+	if len(n.problems) > 0 {
+		os.Exit(1)
+	}
+}
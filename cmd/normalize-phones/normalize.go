@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/phone"
+	"sort"
+	"strings"
+)
+
+// normalizer works out the E.164 form of stored numbers. Values it cannot
+// parse, and changes that would give two rows the same unique value, are
+// left alone and reported in problems for an operator to resolve.
+type normalizer struct {
+	parser   *phone.Parser
+	problems []string
+}
+
+func (n *normalizer) problem(format string, args ...interface{}) {
+	n.problems = append(n.problems, fmt.Sprintf(format, args...))
+}
+
+// number returns the E.164 form of stored, or stored itself when it cannot
+// be parsed.
+func (n *normalizer) number(what, stored string) string {
+	number, err := n.parser.Parse(stored)
+	if err != nil {
+		n.problem("%s: %q: %v", what, stored, err)
+		return stored
+	}
+	return number.E164
+}
+
+// numbers maps each stored value that is not in E.164 form to its
+// normalized value. It suits columns without a unique constraint. Empty
+// values are the numbers blanked when an account was purged and are left
+// as they are.
+func (n *normalizer) numbers(what string, stored []string) map[string]string {
+	changes := map[string]string{}
+	for _, value := range stored {
+		if value == "" {
+			continue
+		}
+		if normalized := n.number(what, value); normalized != value {
+			changes[value] = normalized
+		}
+	}
+	return changes
+}
+
+// users returns the new number of each user whose number is not in E.164
+// form. users.phone_number is unique, so when several accounts, deleted
+// ones included, normalize to the same number none of them is changed:
+// they belong to one person or to a recycled number, and only an operator
+// can tell which account to keep.
+func (n *normalizer) users(users []model.User) map[uint]string {
+	holders := map[string][]model.User{}
+	for _, user := range users {
+		normalized := n.number(fmt.Sprintf("user %d", user.ID), user.PhoneNumber)
+		holders[normalized] = append(holders[normalized], user)
+	}
+
+	changes := map[uint]string{}
+	for _, number := range sortedKeys(holders) {
+		group := holders[number]
+		if len(group) > 1 {
+			ids := make([]string, 0, len(group))
+			for _, user := range group {
+				ids = append(ids, fmt.Sprintf("%d (%q)", user.ID, user.PhoneNumber))
+			}
+			n.problem("users %s all normalize to %s; merge or delete all but one and run again", strings.Join(ids, ", "), number)
+			continue
+		}
+		if user := group[0]; user.PhoneNumber != number {
+			changes[user.ID] = number
+		}
+	}
+	return changes
+}
+
+// rules returns the new value of each phone rule that is not in the form
+// the admin API stores. Rules are unique per list, match type and value;
+// when several would become the same rule none of them is changed.
+func (n *normalizer) rules(rules []model.PhoneRule) map[uint]string {
+	type ruleKey struct{ list, matchType, value string }
+
+	matching := map[ruleKey][]model.PhoneRule{}
+	var keys []ruleKey
+	for _, rule := range rules {
+		value := rule.Value
+		switch rule.MatchType {
+		case model.PhoneMatchExact:
+			value = n.number(fmt.Sprintf("phone rule %d", rule.ID), rule.Value)
+		case model.PhoneMatchPrefix:
+			if prefix := phone.NormalizePrefix(rule.Value); prefix != "+" {
+				value = prefix
+			} else {
+				n.problem("phone rule %d: %q: invalid prefix", rule.ID, rule.Value)
+			}
+		}
+
+		key := ruleKey{rule.List, rule.MatchType, value}
+		if _, ok := matching[key]; !ok {
+			keys = append(keys, key)
+		}
+		matching[key] = append(matching[key], rule)
+	}
+
+	changes := map[uint]string{}
+	for _, key := range keys {
+		group := matching[key]
+		if len(group) > 1 {
+			ids := make([]string, 0, len(group))
+			for _, rule := range group {
+				ids = append(ids, fmt.Sprintf("%d (%q)", rule.ID, rule.Value))
+			}
+			n.problem("%s %s rules %s all normalize to %s; delete all but one and run again", key.list, key.matchType, strings.Join(ids, ", "), key.value)
+			continue
+		}
+		if rule := group[0]; rule.Value != key.value {
+			changes[rule.ID] = key.value
+		}
+	}
+	return changes
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/phone"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeUsers(t *testing.T) {
+	n := &normalizer{parser: phone.NewParser("US")}
+
+	changes := n.users([]model.User{
+		{ID: 1, PhoneNumber: "+14155552671"},
+		{ID: 2, PhoneNumber: "14155552672"},
+		{ID: 3, PhoneNumber: "(415) 555-2673"},
+		// 4 and 5 are the same number typed two ways
+		{ID: 4, PhoneNumber: "0014155552674"},
+		{ID: 5, PhoneNumber: "+1 415 555 2674"},
+		{ID: 6, PhoneNumber: "not a number"},
+	})
+
+	want := map[uint]string{2: "+14155552672", 3: "+14155552673"}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+	if len(n.problems) != 2 {
+		t.Errorf("problems = %q, want the unparseable number and the collision", n.problems)
+	}
+}
+
+func TestNormalizeUsersCollidesWithCanonical(t *testing.T) {
+	n := &normalizer{parser: phone.NewParser("US")}
+
+	// The canonical holder keeps the number; the other account is not moved onto it
+	changes := n.users([]model.User{
+		{ID: 1, PhoneNumber: "+14155552671"},
+		{ID: 2, PhoneNumber: "14155552671"},
+	})
+
+	if len(changes) != 0 {
+		t.Errorf("changes = %v, want none", changes)
+	}
+	if len(n.problems) != 1 {
+		t.Errorf("problems = %q, want one collision", n.problems)
+	}
+}
+
+func TestNormalizeRules(t *testing.T) {
+	n := &normalizer{parser: phone.NewParser("US")}
+
+	changes := n.rules([]model.PhoneRule{
+		{ID: 1, List: model.PhoneListDeny, MatchType: model.PhoneMatchExact, Value: "14155552671"},
+		{ID: 2, List: model.PhoneListDeny, MatchType: model.PhoneMatchPrefix, Value: "44"},
+		{ID: 3, List: model.PhoneListDeny, MatchType: model.PhoneMatchPrefix, Value: "+44"},
+		{ID: 4, List: model.PhoneListAllow, MatchType: model.PhoneMatchPrefix, Value: "0049"},
+	})
+
+	want := map[uint]string{1: "+14155552671", 4: "+49"}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+	if len(n.problems) != 1 {
+		t.Errorf("problems = %q, want the duplicate +44 deny rules", n.problems)
+	}
+}
+
+func TestNormalizeNumbers(t *testing.T) {
+	n := &normalizer{parser: phone.NewParser("US")}
+
+	// "" is a request of a purged account
+	changes := n.numbers("otp request", []string{"+14155552671", "14155552672", "garbage", ""})
+
+	want := map[string]string{"14155552672": "+14155552672"}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+	if len(n.problems) != 1 {
+		t.Errorf("problems = %q, want the unparseable number", n.problems)
+	}
+}
@@ -4,12 +4,20 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"otp-auth-service/internal/challenge"
 	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/enrich"
 	"otp-auth-service/internal/handler"
 	"otp-auth-service/internal/middleware"
 	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/phone"
 	"otp-auth-service/internal/repository"
+	"otp-auth-service/internal/sender"
 	"otp-auth-service/internal/service"
+	"otp-auth-service/internal/token"
+	"time"
+	// Profile time zones are validated even on images without tzdata
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
@@ -28,7 +36,7 @@ func main() {
 	// todo: fix ssl mode
 	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", cfg.Database.Postgres.User, cfg.Database.Postgres.Password, cfg.Database.Postgres.Host, cfg.Database.Postgres.Port, cfg.Database.Postgres.DatabaseName)
 
-	db, err := gorm.Open(postgres.Open(dbURL), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dbURL), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
@@ -48,45 +56,217 @@ func main() {
 	}
 
 	// Auto migrate model
-	db.AutoMigrate(&model.User{}, &model.OTPRequest{})
+	db.AutoMigrate(&model.User{}, &model.OTPRequest{}, &model.PhoneRule{}, &model.AuditLog{}, &model.PhoneNumberHistory{}, &model.RefreshToken{}, &model.Session{}, &model.OAuthClient{}, &model.TrustedDevice{})
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	otpRepo := repository.NewOTPRepository(redisClient, db)
+	challengeRepo := repository.NewChallengeRepository(redisClient)
+	phoneRuleRepo := repository.NewPhoneRuleRepository(redisClient, db)
+	auditRepo := repository.NewAuditRepository(db)
+	tokenRepo := repository.NewTokenRepository(redisClient)
+	refreshRepo := repository.NewRefreshTokenRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	deviceRepo := repository.NewTrustedDeviceRepository(db)
+	oauthClientRepo := repository.NewOAuthClientRepository(db)
+	authorizationRepo := repository.NewAuthorizationRepository(redisClient)
+	dpopRepo := repository.NewDPoPRepository(redisClient)
+
+	// Initialize challenge verifier
+	var challengeVerifier challenge.Verifier
+	switch cfg.OTP.Challenge.Provider {
+	case "pow":
+		challengeVerifier = challenge.NewProofOfWork(challengeRepo, cfg.OTP.Challenge.PoWDifficulty, cfg.OTP.Challenge.PoWTTL)
+	case "http":
+		if cfg.OTP.Challenge.VerifyURL == "" {
+			log.Fatal("OTP_CHALLENGE_VERIFY_URL is required for the http challenge provider")
+		}
+		challengeVerifier = challenge.NewHTTPVerifier(cfg.OTP.Challenge.VerifyURL, cfg.OTP.Challenge.SiteKey, cfg.OTP.Challenge.Secret)
+	case "none", "":
+	default:
+		log.Fatalf("unknown challenge provider %q", cfg.OTP.Challenge.Provider)
+	}
+
+	phoneParser := phone.NewParser(cfg.Phone.DefaultRegion)
+
+	// Initialize OTP senders
+	senders := map[string]sender.Sender{
+		sender.ChannelSMS:      sender.NewConsoleSender(sender.ChannelSMS),
+		sender.ChannelVoice:    sender.NewConsoleSender(sender.ChannelVoice),
+		sender.ChannelWhatsApp: sender.NewConsoleSender(sender.ChannelWhatsApp),
+	}
+	channelOrders := [][]string{cfg.OTP.Channels}
+	for _, policy := range cfg.OTP.CountryPolicies {
+		channelOrders = append(channelOrders, policy.Channels)
+	}
+	for _, channels := range channelOrders {
+		for _, channel := range channels {
+			if _, ok := senders[channel]; !ok {
+				log.Fatalf("unknown OTP channel %q", channel)
+			}
+		}
+	}
+
+	// Initialize token signing keys
+	var signingKeys *token.KeySet
+	if cfg.JWT.KeysFile != "" {
+		signingKeys, err = token.LoadKeySet(cfg.JWT.KeysFile)
+	} else {
+		signingKeys, err = token.NewHMACKeySet(cfg.JWT.Secret)
+	}
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+	if _, err := signingKeys.SigningKey(); err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+
+	// Initialize claims enrichers
+	var enrichers []enrich.Enricher
+	if len(cfg.Claims.MetadataKeys) > 0 {
+		enrichers = append(enrichers, enrich.NewMetadataEnricher(cfg.Claims.MetadataKeys))
+	}
+	if cfg.Claims.HookURL != "" {
+		enrichers = append(enrichers, enrich.NewHTTPEnricher(cfg.Claims.HookURL, cfg.Claims.HookSecret, cfg.Claims.MaxSize))
+	}
+	var claimsEnricher enrich.Enricher
+	if len(enrichers) > 0 {
+		claimsEnricher = enrich.NewChain(cfg.Claims.HookTimeout, cfg.Claims.MaxSize, enrichers...)
+	}
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, otpRepo)
+	oauthClientService := service.NewOAuthClientService(oauthClientRepo, tokenRepo, cfg.JWT)
+	dpopService := service.NewDPoPService(dpopRepo, cfg.DPoP, cfg.JWT)
+	tokenValidator := service.NewTokenValidator(tokenRepo, userRepo, oauthClientService, cfg.JWT, cfg.Exchange, signingKeys)
+	authService := service.NewAuthService(userRepo, otpRepo, phoneRuleRepo, tokenRepo, refreshRepo, sessionRepo, deviceRepo, challengeVerifier, phoneParser, senders, cfg.OTP, cfg.Phone, cfg.Device, cfg.JWT, signingKeys, claimsEnricher)
 	userService := service.NewUserService(userRepo)
+	accountService := service.NewAccountService(userRepo, sessionRepo, deviceRepo, otpRepo, auditRepo, cfg.Account)
+	sessionService := service.NewSessionService(sessionRepo, tokenRepo, refreshRepo, cfg.JWT)
+	trustedDeviceService := service.NewTrustedDeviceService(deviceRepo)
+	phoneRuleService := service.NewPhoneRuleService(phoneRuleRepo, phoneParser)
+	otpAdminService := service.NewOTPAdminService(otpRepo, phoneRuleRepo, auditRepo, cfg.OTP)
+	userAdminService := service.NewUserAdminService(userRepo, tokenRepo, refreshRepo, sessionRepo, deviceRepo, auditRepo)
+	introspectionService := service.NewIntrospectionService(oauthClientService, tokenValidator, refreshRepo)
+	oidcService := service.NewOIDCService(authService, userService, oauthClientService, tokenValidator, authorizationRepo, userRepo, cfg.OIDC, cfg.JWT, cfg.Exchange, signingKeys)
+
+	// Purge deleted accounts once their grace period is over
+	go func() {
+		ticker := time.NewTicker(cfg.Account.PurgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purged, err := accountService.PurgeDeleted()
+			if err != nil {
+				log.Printf("Failed to purge deleted accounts: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("Purged %d deleted accounts", purged)
+			}
+		}
+	}()
 
 	// Initialize handler
-	authHandler := handler.NewAuthHandler(authService)
+	authHandler := handler.NewAuthHandler(authService, dpopService, cfg.Cookie, cfg.JWT)
 	userHandler := handler.NewUserHandler(userService)
-	otpStatsHandler := handler.NewOTPStatsHandler(otpRepo)
+	accountHandler := handler.NewAccountHandler(authService, accountService, cfg.Account, cfg.Cookie, cfg.JWT)
+	phoneChangeHandler := handler.NewPhoneChangeHandler(authService, cfg.Cookie, cfg.JWT)
+	sessionHandler := handler.NewSessionHandler(sessionService)
+	trustedDeviceHandler := handler.NewTrustedDeviceHandler(trustedDeviceService)
+	jwksHandler := handler.NewJWKSHandler(signingKeys)
+	otpStatsHandler := handler.NewOTPStatsHandler(otpRepo, phoneParser)
+	phoneRuleHandler := handler.NewPhoneRuleHandler(phoneRuleService)
+	otpAdminHandler := handler.NewOTPAdminHandler(otpAdminService, auditRepo, phoneParser)
+	userAdminHandler := handler.NewUserAdminHandler(userAdminService)
+	oauthClientHandler := handler.NewOAuthClientHandler(oauthClientService)
+	oidcHandler := handler.NewOIDCHandler(oidcService, cfg.OIDC)
+	introspectionHandler := handler.NewIntrospectionHandler(introspectionService)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware()
+	authMiddleware := middleware.NewAuthMiddleware(tokenValidator, dpopService)
+	adminMiddleware := middleware.NewAdminMiddleware(cfg.Admin.APIToken)
 
 	// Setup router
 	router := gin.Default()
+	// Client IPs feed rate limits and sessions, so forwarding headers are
+	// only believed from configured proxies
+	if err := router.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		log.Fatal("Invalid API_HTTP_TRUSTED_PROXIES:", err)
+	}
 
 	// Auth routes
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
 	router.POST("/auth/request-otp", authHandler.RequestOTP)
 	router.POST("/auth/verify-otp", authHandler.VerifyOTP)
+	router.POST("/auth/device-login", authHandler.DeviceLogin)
+	router.POST("/auth/refresh", authHandler.Refresh)
+	router.POST("/auth/logout", authMiddleware.ValidateToken, authHandler.Logout)
+	router.POST("/auth/reauthenticate/request-otp", authMiddleware.ValidateToken, authHandler.RequestReauthentication)
+	router.POST("/auth/reauthenticate", authMiddleware.ValidateToken, authHandler.Reauthenticate)
+
+	// OpenID Connect provider routes
+	if cfg.OIDC.Enabled {
+		router.GET("/.well-known/openid-configuration", oidcHandler.Discovery)
+		router.GET("/oauth/authorize", oidcHandler.Authorize)
+		router.POST("/oauth/authorize/complete", oidcHandler.CompleteAuthorization)
+	}
+
+	// Token routes for OAuth and service clients
+	router.POST("/oauth/token", oidcHandler.Token)
+	router.POST("/oauth/introspect", introspectionHandler.Introspect)
+	router.GET("/oauth/userinfo", authMiddleware.ValidateClientToken, oidcHandler.UserInfo)
+	router.POST("/oauth/userinfo", authMiddleware.ValidateClientToken, oidcHandler.UserInfo)
 
 	// Protected routes
 	router.GET("/me", authMiddleware.ValidateToken, userHandler.GetMe)
+	router.PATCH("/me", authMiddleware.ValidateToken, userHandler.UpdateMe)
+	router.DELETE("/me", authMiddleware.ValidateToken, accountHandler.DeleteMe)
+	router.POST("/me/delete/request-otp", authMiddleware.ValidateToken, accountHandler.RequestDeletionOTP)
+	router.GET("/me/export", authMiddleware.ValidateToken, accountHandler.ExportMe)
+	// Moving the account to another number needs a recent OTP, not just a
+	// token or a trusted device
+	requireRecentOTP := middleware.RequireAuthentication(cfg.StepUp.MaxAge, service.ACROTP)
+	router.POST("/me/phone/change", authMiddleware.ValidateToken, requireRecentOTP, phoneChangeHandler.StartPhoneChange)
+	router.POST("/me/phone/change/confirm", authMiddleware.ValidateToken, phoneChangeHandler.ConfirmPhoneChange)
+	router.GET("/me/sessions", authMiddleware.ValidateToken, sessionHandler.ListSessions)
+	router.DELETE("/me/sessions", authMiddleware.ValidateToken, sessionHandler.RevokeOtherSessions)
+	router.DELETE("/me/sessions/:id", authMiddleware.ValidateToken, sessionHandler.RevokeSession)
+	router.GET("/me/devices", authMiddleware.ValidateToken, trustedDeviceHandler.ListDevices)
+	router.DELETE("/me/devices", authMiddleware.ValidateToken, trustedDeviceHandler.RevokeAllDevices)
+	router.DELETE("/me/devices/:id", authMiddleware.ValidateToken, trustedDeviceHandler.RevokeDevice)
 
 	// User routes (protected)
 	userRoutes := router.Group("/users")
-	userRoutes.Use(authMiddleware.ValidateToken)
+	userRoutes.Use(authMiddleware.ValidateServiceToken)
 	{
+		// Users may read themselves; everyone else needs users:read and,
+		// unless they are a service client, the admin role
 		userRoutes.GET("/:id", userHandler.GetUser)
-		userRoutes.GET("/", userHandler.GetUsers)
+		userRoutes.GET("/", middleware.RequireScope(service.ScopeUsersRead), middleware.RequireRole(model.RoleAdmin), userHandler.GetUsers)
 	}
 
 	// OTP stats route (public for monitoring)
 	router.GET("/otp/stats", otpStatsHandler.GetOTPStats)
 
+	// Admin routes
+	adminRoutes := router.Group("/admin")
+	adminRoutes.Use(adminMiddleware.RequireAdmin)
+	{
+		adminRoutes.GET("/phone-rules", phoneRuleHandler.ListPhoneRules)
+		adminRoutes.POST("/phone-rules", phoneRuleHandler.CreatePhoneRule)
+		adminRoutes.DELETE("/phone-rules/:id", phoneRuleHandler.DeletePhoneRule)
+		adminRoutes.GET("/otp/state", otpAdminHandler.GetOTPState)
+		adminRoutes.POST("/otp/reset", otpAdminHandler.ResetOTPState)
+		adminRoutes.GET("/audit-logs", otpAdminHandler.GetAuditLogs)
+		adminRoutes.POST("/users/:id/revoke-tokens", userAdminHandler.RevokeUserTokens)
+		adminRoutes.PUT("/users/:id/roles", userAdminHandler.SetUserRoles)
+		adminRoutes.PUT("/users/:id/metadata", userAdminHandler.SetUserMetadata)
+		adminRoutes.POST("/users/:id/restore", userAdminHandler.RestoreUser)
+		adminRoutes.PUT("/users/:id/status", userAdminHandler.SetUserStatus)
+		adminRoutes.GET("/oauth-clients", oauthClientHandler.ListOAuthClients)
+		adminRoutes.POST("/oauth-clients", oauthClientHandler.CreateOAuthClient)
+		adminRoutes.POST("/oauth-clients/:client_id/rotate-secret", oauthClientHandler.RotateOAuthClientSecret)
+		adminRoutes.DELETE("/oauth-clients/:client_id", oauthClientHandler.RevokeOAuthClient)
+	}
+
 	// Start server
 	router.Run(fmt.Sprintf("%s:%d", cfg.HTTP.APIHost, cfg.HTTP.APIPort))
 }
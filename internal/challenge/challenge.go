@@ -0,0 +1,28 @@
+package challenge
+
+import "errors"
+
+var ErrInvalidSolution = errors.New("invalid challenge solution")
+
+const (
+	TypeCaptcha     = "captcha"
+	TypeProofOfWork = "pow"
+)
+
+// Challenge describes what a client must solve before it may request
+// another OTP. Only the fields relevant to Type are populated.
+type Challenge struct {
+	Type       string `json:"type"`
+	SiteKey    string `json:"site_key,omitempty"`
+	Token      string `json:"token,omitempty"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	ExpiresIn  int    `json:"expires_in,omitempty"`
+}
+
+// Verifier issues challenges and checks the solutions submitted by clients.
+// Verify returns ErrInvalidSolution when the solution is rejected; any other
+// error means the verifier itself could not decide.
+type Verifier interface {
+	Issue() (*Challenge, error)
+	Verify(solution, remoteIP string) error
+}
@@ -0,0 +1,84 @@
+package challenge
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// siteverify stands in for an hCaptcha/Turnstile endpoint. It accepts the
+// "good" response and answers status for any other.
+func siteverify(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parsing form: %v", err)
+		}
+		if got := r.PostForm.Get("secret"); got != "secret" {
+			t.Errorf("secret = %q, want secret", got)
+		}
+		if got := r.PostForm.Get("sitekey"); got != "site" {
+			t.Errorf("sitekey = %q, want site", got)
+		}
+		if got := r.PostForm.Get("remoteip"); got != "203.0.113.7" {
+			t.Errorf("remoteip = %q, want 203.0.113.7", got)
+		}
+
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		success := r.PostForm.Get("response") == "good"
+		fmt.Fprintf(w, `{"success": %t, "error-codes": []}`, success)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPVerifierIssue(t *testing.T) {
+	verifier := NewHTTPVerifier("http://unused", "site", "secret")
+
+	issued, err := verifier.Issue()
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if issued.Type != TypeCaptcha || issued.SiteKey != "site" {
+		t.Errorf("Issue = %+v, want a captcha for site", issued)
+	}
+}
+
+func TestHTTPVerifierVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		solution string
+		wantErr  error
+	}{
+		{"accepted", http.StatusOK, "good", nil},
+		{"rejected", http.StatusOK, "bad", ErrInvalidSolution},
+		{"empty solution", http.StatusOK, "", ErrInvalidSolution},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := siteverify(t, tt.status)
+			verifier := NewHTTPVerifier(server.URL, "site", "secret")
+
+			if err := verifier.Verify(tt.solution, "203.0.113.7"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPVerifierNon200(t *testing.T) {
+	server := siteverify(t, http.StatusServiceUnavailable)
+	verifier := NewHTTPVerifier(server.URL, "site", "secret")
+
+	// An outage is not the client's fault, so it must not read as a wrong answer
+	err := verifier.Verify("good", "203.0.113.7")
+	if err == nil || errors.Is(err, ErrInvalidSolution) {
+		t.Errorf("Verify = %v, want a verifier error", err)
+	}
+}
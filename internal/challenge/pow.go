@@ -0,0 +1,76 @@
+package challenge
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/bits"
+	"otp-auth-service/internal/repository"
+	"strings"
+	"time"
+)
+
+// proofOfWork is a hashcash style verifier. The client receives a random
+// token and must find a nonce such that sha256("<token>:<nonce>") starts
+// with at least difficulty zero bits. Tokens are single use.
+type proofOfWork struct {
+	store      repository.ChallengeRepository
+	difficulty int
+	ttl        time.Duration
+}
+
+func NewProofOfWork(store repository.ChallengeRepository, difficulty int, ttl time.Duration) Verifier {
+	return &proofOfWork{store: store, difficulty: difficulty, ttl: ttl}
+}
+
+func (p *proofOfWork) Issue() (*Challenge, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := p.store.StoreChallenge(token, p.ttl); err != nil {
+		return nil, err
+	}
+
+	return &Challenge{
+		Type:       TypeProofOfWork,
+		Token:      token,
+		Difficulty: p.difficulty,
+		ExpiresIn:  int(p.ttl.Seconds()),
+	}, nil
+}
+
+// Verify expects the solution in the form "<token>:<nonce>".
+func (p *proofOfWork) Verify(solution, _ string) error {
+	token, nonce, ok := strings.Cut(solution, ":")
+	if !ok || token == "" || nonce == "" {
+		return ErrInvalidSolution
+	}
+
+	sum := sha256.Sum256([]byte(token + ":" + nonce))
+	if leadingZeroBits(sum[:]) < p.difficulty {
+		return ErrInvalidSolution
+	}
+
+	consumed, err := p.store.ConsumeChallenge(token)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidSolution
+	}
+	return nil
+}
+
+func leadingZeroBits(b []byte) int {
+	n := 0
+	for _, x := range b {
+		if x != 0 {
+			return n + bits.LeadingZeros8(x)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,166 @@
+package challenge
+
+import (
+	"crypto/sha256"
+	"errors"
+	"otp-auth-service/internal/repository"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// memoryChallenges keeps tokens in memory with an adjustable clock.
+type memoryChallenges struct {
+	repository.ChallengeRepository
+	now     time.Time
+	expires map[string]time.Time
+}
+
+func newMemoryChallenges() *memoryChallenges {
+	return &memoryChallenges{
+		now:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		expires: map[string]time.Time{},
+	}
+}
+
+func (m *memoryChallenges) StoreChallenge(token string, expiration time.Duration) error {
+	m.expires[token] = m.now.Add(expiration)
+	return nil
+}
+
+func (m *memoryChallenges) ConsumeChallenge(token string) (bool, error) {
+	expiresAt, ok := m.expires[token]
+	delete(m.expires, token)
+	return ok && m.now.Before(expiresAt), nil
+}
+
+// solve finds a nonce meeting difficulty, or one missing it when miss is set.
+func solve(token string, difficulty int, miss bool) string {
+	for nonce := 0; ; nonce++ {
+		solution := token + ":" + strconv.Itoa(nonce)
+		sum := sha256.Sum256([]byte(solution))
+		if (leadingZeroBits(sum[:]) >= difficulty) != miss {
+			return solution
+		}
+	}
+}
+
+func TestProofOfWorkIssue(t *testing.T) {
+	store := newMemoryChallenges()
+	verifier := NewProofOfWork(store, 8, 5*time.Minute)
+
+	issued, err := verifier.Issue()
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if issued.Type != TypeProofOfWork || issued.Difficulty != 8 || issued.ExpiresIn != 300 {
+		t.Errorf("Issue = %+v, want pow, difficulty 8, expires in 300", issued)
+	}
+	if len(issued.Token) != 32 {
+		t.Errorf("token %q, want 32 hex characters", issued.Token)
+	}
+	if _, ok := store.expires[issued.Token]; !ok {
+		t.Error("issued token was not stored")
+	}
+
+	again, err := verifier.Issue()
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if again.Token == issued.Token {
+		t.Error("Issue returned the same token twice")
+	}
+}
+
+func TestProofOfWorkVerify(t *testing.T) {
+	store := newMemoryChallenges()
+	verifier := NewProofOfWork(store, 8, 5*time.Minute)
+
+	issued, err := verifier.Issue()
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	solution := solve(issued.Token, issued.Difficulty, false)
+
+	if err := verifier.Verify(solution, ""); err != nil {
+		t.Fatalf("Verify = %v, want nil", err)
+	}
+	// Tokens are single use
+	if err := verifier.Verify(solution, ""); !errors.Is(err, ErrInvalidSolution) {
+		t.Errorf("second Verify = %v, want ErrInvalidSolution", err)
+	}
+}
+
+func TestProofOfWorkRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		solution func(token string) string
+	}{
+		{"malformed", func(token string) string { return token }},
+		{"empty nonce", func(token string) string { return token + ":" }},
+		{"below difficulty", func(token string) string { return solve(token, 8, true) }},
+		{"unknown token", func(string) string { return solve("deadbeef", 8, false) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryChallenges()
+			verifier := NewProofOfWork(store, 8, 5*time.Minute)
+			issued, err := verifier.Issue()
+			if err != nil {
+				t.Fatalf("Issue: %v", err)
+			}
+
+			if err := verifier.Verify(tt.solution(issued.Token), ""); !errors.Is(err, ErrInvalidSolution) {
+				t.Errorf("Verify = %v, want ErrInvalidSolution", err)
+			}
+		})
+	}
+}
+
+func TestProofOfWorkBelowDifficultyKeepsToken(t *testing.T) {
+	store := newMemoryChallenges()
+	verifier := NewProofOfWork(store, 8, 5*time.Minute)
+	issued, err := verifier.Issue()
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	// A wrong nonce must not burn the token before the work is checked
+	if err := verifier.Verify(solve(issued.Token, 8, true), ""); !errors.Is(err, ErrInvalidSolution) {
+		t.Fatalf("Verify = %v, want ErrInvalidSolution", err)
+	}
+	if err := verifier.Verify(solve(issued.Token, 8, false), ""); err != nil {
+		t.Errorf("Verify = %v, want nil", err)
+	}
+}
+
+func TestProofOfWorkExpired(t *testing.T) {
+	store := newMemoryChallenges()
+	verifier := NewProofOfWork(store, 8, 5*time.Minute)
+	issued, err := verifier.Issue()
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	store.now = store.now.Add(5*time.Minute + time.Second)
+	if err := verifier.Verify(solve(issued.Token, 8, false), ""); !errors.Is(err, ErrInvalidSolution) {
+		t.Errorf("Verify = %v, want ErrInvalidSolution", err)
+	}
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want int
+	}{
+		{[]byte{0x80}, 0},
+		{[]byte{0x01}, 7},
+		{[]byte{0x00, 0x0f}, 12},
+		{[]byte{0x00, 0x00}, 16},
+	}
+	for _, tt := range tests {
+		if got := leadingZeroBits(tt.in); got != tt.want {
+			t.Errorf("leadingZeroBits(%x) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
@@ -1,13 +1,31 @@
 package config
 
+import "time"
+
 type Config struct {
 	HTTP     HTTP
 	Database Database
+	OTP      OTP
+	Phone    Phone
+	Device   Device
+	Account  Account
+	JWT      JWT
+	OIDC     OIDC
+	DPoP     DPoP
+	StepUp   StepUp
+	Claims   Claims
+	Exchange TokenExchange
+	Cookie   Cookie
+	Admin    Admin
 }
 
 type HTTP struct {
 	APIHost string
 	APIPort int
+	// TrustedProxies are the addresses or CIDRs whose X-Forwarded-For and
+	// X-Real-IP headers name the client. With none, the client IP is always
+	// the peer address.
+	TrustedProxies []string
 }
 
 type Database struct {
@@ -29,3 +47,167 @@ type Redis struct {
 	Password string
 	Database int
 }
+
+type OTP struct {
+	Length     int
+	Expiry     time.Duration
+	RateLimit  int
+	RateWindow time.Duration
+	// MaxAttempts is how many wrong guesses void an OTP.
+	MaxAttempts int
+	// Channels is the default delivery order, e.g. sms then voice.
+	Channels []string
+	// TestCode is the fixed OTP accepted for numbers on the test list.
+	TestCode string
+	// AllowedCountries restricts OTPs to these ISO regions when non-empty.
+	// DeniedCountries always wins.
+	AllowedCountries []string
+	DeniedCountries  []string
+	// CountryPolicies holds per-region overrides keyed by ISO region.
+	CountryPolicies map[string]CountryPolicy
+	Challenge       Challenge
+}
+
+// CountryPolicy overrides OTP settings for one country. Zero values inherit
+// the global setting.
+type CountryPolicy struct {
+	Length     int
+	Expiry     time.Duration
+	Channels   []string
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+// Challenge configures the step-up gate that asks clients to solve a
+// CAPTCHA or proof-of-work puzzle once OTP traffic looks suspicious.
+type Challenge struct {
+	// Provider is one of "none", "pow" or "http".
+	Provider string
+	// PhoneThreshold and IPThreshold are how many requests a phone number
+	// (in OTP_RATE_WINDOW) or client IP (in IPWindow) may make without a
+	// challenge: once that many were made, the next ones need one. Zero
+	// disables the check.
+	PhoneThreshold int
+	IPThreshold    int
+	IPWindow       time.Duration
+	VerifyURL      string
+	SiteKey        string
+	Secret         string
+	PoWDifficulty  int
+	PoWTTL         time.Duration
+}
+
+type Phone struct {
+	// DefaultRegion is the ISO 3166-1 alpha-2 region used for numbers
+	// entered without an international prefix.
+	DefaultRegion string
+	// ChangeVerifyOldNumber also requires an OTP sent to the current number
+	// when a user changes their phone number.
+	ChangeVerifyOldNumber bool
+}
+
+// Device configures trusted devices, which log in without an OTP.
+type Device struct {
+	// TrustTTL is how long a device stays trusted after an OTP login. Zero
+	// disables trusted devices.
+	TrustTTL time.Duration
+}
+
+// Account configures account deletion.
+type Account struct {
+	// DeletionGrace is how long a deleted account is kept before it is
+	// purged for good.
+	DeletionGrace time.Duration
+	// PurgeInterval is how often accounts past their grace period are
+	// looked for.
+	PurgeInterval time.Duration
+}
+
+type JWT struct {
+	// Secret is the HMAC signing key. When SecretFile is set the key is read
+	// from that file instead.
+	Secret     string
+	SecretFile string
+	// KeysFile points at a JSON manifest of asymmetric signing keys and their
+	// rotation schedule. It takes precedence over Secret.
+	KeysFile string
+	Issuer   string
+	Audience string
+	// TTL is the lifetime of access tokens; RefreshTTL that of refresh tokens.
+	TTL        time.Duration
+	RefreshTTL time.Duration
+	// Leeway tolerates clock skew when checking exp, nbf and iat.
+	Leeway time.Duration
+}
+
+// OIDC configures the OpenID Connect provider. The issuer and endpoint URLs
+// are derived from JWT.Issuer, which must then be the service's public URL.
+type OIDC struct {
+	Enabled bool
+	// LoginURL is the page that runs the OTP login for an authorization
+	// request; it receives the request ID as the request_id query parameter.
+	LoginURL   string
+	RequestTTL time.Duration
+	CodeTTL    time.Duration
+}
+
+// DPoP configures sender-constrained access tokens (RFC 9449). Clients opt
+// in by sending a DPoP proof when they log in.
+type DPoP struct {
+	// ProofLifetime is how long after its iat a proof is accepted. Used
+	// proof IDs are remembered for as long.
+	ProofLifetime time.Duration
+	// RequireNonce makes proofs carry a nonce issued by the server in the
+	// DPoP-Nonce header; NonceTTL is how long each nonce is accepted.
+	RequireNonce bool
+	NonceTTL     time.Duration
+}
+
+// StepUp configures how recent a strong authentication sensitive
+// endpoints such as changing the phone number require.
+type StepUp struct {
+	MaxAge time.Duration
+}
+
+// Claims configures custom claims added to first-party access tokens under
+// the ext claim.
+type Claims struct {
+	// MetadataKeys are the user metadata keys copied into tokens.
+	MetadataKeys []string
+	// HookURL, when set, is called for more claims on every token issued.
+	HookURL    string
+	HookSecret string
+	// HookTimeout bounds each enricher; one that fails or runs late is
+	// skipped and the token is issued without its claims.
+	HookTimeout time.Duration
+	// MaxSize caps the encoded ext claim in bytes.
+	MaxSize int
+}
+
+// TokenExchange configures RFC 8693 token exchange, which trades a user's
+// access token for a narrower one meant for another service.
+type TokenExchange struct {
+	// Audiences are the services tokens may be exchanged for. Token exchange
+	// is disabled while it is empty.
+	Audiences []string
+	// TTL caps the lifetime of exchanged tokens; they never outlive the
+	// subject token either.
+	TTL time.Duration
+}
+
+// Cookie configures browser sessions, which keep their tokens in HttpOnly
+// cookies instead of handing them to scripts.
+type Cookie struct {
+	Domain string
+	// Secure should only be turned off for local development over plain
+	// HTTP.
+	Secure bool
+	// SameSite is "strict", "lax" or "none"; "none" requires Secure.
+	SameSite string
+}
+
+type Admin struct {
+	// APIToken guards the /admin routes. Admin routes reject every request
+	// while it is empty.
+	APIToken string
+}
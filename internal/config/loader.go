@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -38,6 +39,14 @@ func loadStringSlice(envName string) []string {
 	return viper.GetStringSlice(envName)
 }
 
+// loadList reads a comma or space separated list.
+func loadList(envName string) []string {
+	validate(envName)
+	return strings.FieldsFunc(viper.GetString(envName), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
 func validate(envName string) {
 	exists := viper.IsSet(envName)
 	if !exists {
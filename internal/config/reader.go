@@ -1,9 +1,15 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
-
 	"fmt"
+	"net/url"
+	"os"
+	"otp-auth-service/internal/token"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -15,6 +21,7 @@ func Load() (*Config, error) {
 	viper.AllowEmptyEnv(true)
 
 	viper.AutomaticEnv()
+	setDefaults()
 	if err := viper.ReadInConfig(); err != nil {
 		if !errors.As(err, &viper.ConfigFileNotFoundError{}) {
 			return nil, fmt.Errorf("reading config: %w", err)
@@ -24,6 +31,8 @@ func Load() (*Config, error) {
 	httpCfg := HTTP{
 		APIHost: loadString("API_HTTP_HOST"),
 		APIPort: loadInt("API_HTTP_PORT"),
+
+		TrustedProxies: loadList("API_HTTP_TRUSTED_PROXIES"),
 	}
 
 	postgresCfg := Postgres{
@@ -41,11 +50,331 @@ func Load() (*Config, error) {
 		Database: loadInt("DATABASE_REDIS_DATABASE"),
 	}
 
+	countryPolicies, err := loadCountryPolicies("OTP_COUNTRY_POLICIES")
+	if err != nil {
+		return nil, err
+	}
+
+	otpCfg := OTP{
+		Length:      loadInt("OTP_LENGTH"),
+		Expiry:      loadDuration("OTP_EXPIRY"),
+		RateLimit:   loadInt("OTP_RATE_LIMIT"),
+		RateWindow:  loadDuration("OTP_RATE_WINDOW"),
+		MaxAttempts: loadInt("OTP_MAX_ATTEMPTS"),
+		Channels:    loadList("OTP_CHANNELS"),
+		TestCode:    loadString("OTP_TEST_NUMBER_CODE"),
+
+		AllowedCountries: upper(loadList("OTP_ALLOWED_COUNTRIES")),
+		DeniedCountries:  upper(loadList("OTP_DENIED_COUNTRIES")),
+		CountryPolicies:  countryPolicies,
+
+		Challenge: Challenge{
+			Provider:       loadString("OTP_CHALLENGE_PROVIDER"),
+			PhoneThreshold: loadInt("OTP_CHALLENGE_PHONE_THRESHOLD"),
+			IPThreshold:    loadInt("OTP_CHALLENGE_IP_THRESHOLD"),
+			IPWindow:       loadDuration("OTP_CHALLENGE_IP_WINDOW"),
+			VerifyURL:      loadString("OTP_CHALLENGE_VERIFY_URL"),
+			SiteKey:        loadString("OTP_CHALLENGE_SITE_KEY"),
+			Secret:         loadString("OTP_CHALLENGE_SECRET"),
+			PoWDifficulty:  loadInt("OTP_CHALLENGE_POW_DIFFICULTY"),
+			PoWTTL:         loadDuration("OTP_CHALLENGE_POW_TTL"),
+		},
+	}
+	if otpCfg.MaxAttempts <= 0 {
+		return nil, errors.New("OTP_MAX_ATTEMPTS must be positive")
+	}
+
+	phoneCfg := Phone{
+		DefaultRegion:         loadString("PHONE_DEFAULT_REGION"),
+		ChangeVerifyOldNumber: loadBool("PHONE_CHANGE_VERIFY_OLD_NUMBER"),
+	}
+
+	deviceCfg := Device{
+		TrustTTL: loadDuration("DEVICE_TRUST_TTL"),
+	}
+	if deviceCfg.TrustTTL < 0 {
+		return nil, errors.New("DEVICE_TRUST_TTL must not be negative")
+	}
+
+	accountCfg := Account{
+		DeletionGrace: loadDuration("ACCOUNT_DELETION_GRACE"),
+		PurgeInterval: loadDuration("ACCOUNT_PURGE_INTERVAL"),
+	}
+	if accountCfg.DeletionGrace < 0 {
+		return nil, errors.New("ACCOUNT_DELETION_GRACE must not be negative")
+	}
+	if accountCfg.PurgeInterval <= 0 {
+		return nil, errors.New("ACCOUNT_PURGE_INTERVAL must be positive")
+	}
+
+	jwtCfg, err := loadJWT()
+	if err != nil {
+		return nil, err
+	}
+
+	oidcCfg, err := loadOIDC(jwtCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dpopCfg := DPoP{
+		ProofLifetime: loadDuration("DPOP_PROOF_LIFETIME"),
+		RequireNonce:  loadBool("DPOP_REQUIRE_NONCE"),
+		NonceTTL:      loadDuration("DPOP_NONCE_TTL"),
+	}
+	if dpopCfg.ProofLifetime <= 0 || dpopCfg.NonceTTL <= 0 {
+		return nil, errors.New("DPOP_PROOF_LIFETIME and DPOP_NONCE_TTL must be positive")
+	}
+
+	stepUpCfg := StepUp{
+		MaxAge: loadDuration("STEP_UP_MAX_AGE"),
+	}
+	if stepUpCfg.MaxAge < 0 {
+		return nil, errors.New("STEP_UP_MAX_AGE must not be negative")
+	}
+
+	claimsCfg := Claims{
+		MetadataKeys: loadList("CLAIMS_METADATA_KEYS"),
+		HookURL:      loadString("CLAIMS_HOOK_URL"),
+		HookSecret:   loadString("CLAIMS_HOOK_SECRET"),
+		HookTimeout:  loadDuration("CLAIMS_HOOK_TIMEOUT"),
+		MaxSize:      loadInt("CLAIMS_MAX_SIZE"),
+	}
+	if claimsCfg.HookTimeout <= 0 || claimsCfg.MaxSize <= 0 {
+		return nil, errors.New("CLAIMS_HOOK_TIMEOUT and CLAIMS_MAX_SIZE must be positive")
+	}
+
+	exchangeCfg := TokenExchange{
+		Audiences: loadList("TOKEN_EXCHANGE_AUDIENCES"),
+		TTL:       loadDuration("TOKEN_EXCHANGE_TTL"),
+	}
+	if exchangeCfg.TTL <= 0 {
+		return nil, errors.New("TOKEN_EXCHANGE_TTL must be positive")
+	}
+	// Exchanged tokens must not be accepted by this service's own API
+	if slices.Contains(exchangeCfg.Audiences, jwtCfg.Audience) {
+		return nil, errors.New("TOKEN_EXCHANGE_AUDIENCES must not include JWT_AUDIENCE")
+	}
+
+	cookieCfg := Cookie{
+		Domain:   loadString("COOKIE_DOMAIN"),
+		Secure:   loadBool("COOKIE_SECURE"),
+		SameSite: strings.ToLower(loadString("COOKIE_SAME_SITE")),
+	}
+	switch cookieCfg.SameSite {
+	case "strict", "lax":
+	case "none":
+		if !cookieCfg.Secure {
+			return nil, errors.New("COOKIE_SAME_SITE=none requires COOKIE_SECURE")
+		}
+	default:
+		return nil, fmt.Errorf("unknown COOKIE_SAME_SITE %q", cookieCfg.SameSite)
+	}
+
+	adminCfg := Admin{
+		APIToken: loadString("ADMIN_API_TOKEN"),
+	}
+
 	return &Config{
 		HTTP: httpCfg,
 		Database: Database{
 			Postgres: postgresCfg,
 			Redis:    redisCfg,
 		},
+		OTP:      otpCfg,
+		Phone:    phoneCfg,
+		Device:   deviceCfg,
+		Account:  accountCfg,
+		JWT:      jwtCfg,
+		OIDC:     oidcCfg,
+		DPoP:     dpopCfg,
+		StepUp:   stepUpCfg,
+		Claims:   claimsCfg,
+		Exchange: exchangeCfg,
+		Cookie:   cookieCfg,
+		Admin:    adminCfg,
 	}, nil
 }
+
+// setDefaults registers fallbacks for optional settings so that existing
+// deployments keep working without new environment variables.
+func setDefaults() {
+	viper.SetDefault("API_HTTP_TRUSTED_PROXIES", "")
+
+	viper.SetDefault("OTP_LENGTH", 6)
+	viper.SetDefault("OTP_EXPIRY", 2*time.Minute)
+	viper.SetDefault("OTP_MAX_ATTEMPTS", 5)
+	viper.SetDefault("OTP_RATE_LIMIT", 3)
+	viper.SetDefault("OTP_RATE_WINDOW", 10*time.Minute)
+	viper.SetDefault("OTP_CHANNELS", "sms")
+	viper.SetDefault("OTP_TEST_NUMBER_CODE", "")
+	viper.SetDefault("OTP_ALLOWED_COUNTRIES", "")
+	viper.SetDefault("OTP_DENIED_COUNTRIES", "")
+	viper.SetDefault("OTP_COUNTRY_POLICIES", "")
+
+	viper.SetDefault("OTP_CHALLENGE_PROVIDER", "none")
+	viper.SetDefault("OTP_CHALLENGE_PHONE_THRESHOLD", 2)
+	viper.SetDefault("OTP_CHALLENGE_IP_THRESHOLD", 10)
+	viper.SetDefault("OTP_CHALLENGE_IP_WINDOW", 10*time.Minute)
+	viper.SetDefault("OTP_CHALLENGE_VERIFY_URL", "")
+	viper.SetDefault("OTP_CHALLENGE_SITE_KEY", "")
+	viper.SetDefault("OTP_CHALLENGE_SECRET", "")
+	viper.SetDefault("OTP_CHALLENGE_POW_DIFFICULTY", 20)
+	viper.SetDefault("OTP_CHALLENGE_POW_TTL", 5*time.Minute)
+
+	viper.SetDefault("PHONE_DEFAULT_REGION", "US")
+	viper.SetDefault("PHONE_CHANGE_VERIFY_OLD_NUMBER", false)
+
+	viper.SetDefault("DEVICE_TRUST_TTL", 30*24*time.Hour)
+
+	viper.SetDefault("ACCOUNT_DELETION_GRACE", 30*24*time.Hour)
+	viper.SetDefault("ACCOUNT_PURGE_INTERVAL", time.Hour)
+
+	viper.SetDefault("JWT_SECRET", "")
+	viper.SetDefault("JWT_SECRET_FILE", "")
+	viper.SetDefault("JWT_KEYS_FILE", "")
+	viper.SetDefault("JWT_ISSUER", "otp-auth-service")
+	viper.SetDefault("JWT_AUDIENCE", "otp-auth-service")
+	viper.SetDefault("JWT_TTL", 15*time.Minute)
+	viper.SetDefault("JWT_REFRESH_TTL", 30*24*time.Hour)
+	viper.SetDefault("JWT_LEEWAY", 30*time.Second)
+
+	viper.SetDefault("OIDC_ENABLED", false)
+	viper.SetDefault("OIDC_LOGIN_URL", "")
+	viper.SetDefault("OIDC_REQUEST_TTL", 10*time.Minute)
+	viper.SetDefault("OIDC_CODE_TTL", time.Minute)
+
+	viper.SetDefault("DPOP_PROOF_LIFETIME", time.Minute)
+	viper.SetDefault("DPOP_REQUIRE_NONCE", false)
+	viper.SetDefault("DPOP_NONCE_TTL", 5*time.Minute)
+
+	viper.SetDefault("STEP_UP_MAX_AGE", 10*time.Minute)
+
+	viper.SetDefault("CLAIMS_METADATA_KEYS", "")
+	viper.SetDefault("CLAIMS_HOOK_URL", "")
+	viper.SetDefault("CLAIMS_HOOK_SECRET", "")
+	viper.SetDefault("CLAIMS_HOOK_TIMEOUT", 500*time.Millisecond)
+	viper.SetDefault("CLAIMS_MAX_SIZE", 1024)
+
+	viper.SetDefault("TOKEN_EXCHANGE_AUDIENCES", "")
+	viper.SetDefault("TOKEN_EXCHANGE_TTL", 5*time.Minute)
+
+	viper.SetDefault("COOKIE_DOMAIN", "")
+	viper.SetDefault("COOKIE_SECURE", true)
+	viper.SetDefault("COOKIE_SAME_SITE", "lax")
+
+	viper.SetDefault("ADMIN_API_TOKEN", "")
+}
+
+// loadJWT fails when no signing key is configured, so the service never
+// signs tokens with an empty key.
+func loadJWT() (JWT, error) {
+	cfg := JWT{
+		Secret:     loadString("JWT_SECRET"),
+		SecretFile: loadString("JWT_SECRET_FILE"),
+		KeysFile:   loadString("JWT_KEYS_FILE"),
+		Issuer:     loadString("JWT_ISSUER"),
+		Audience:   loadString("JWT_AUDIENCE"),
+		TTL:        loadDuration("JWT_TTL"),
+		RefreshTTL: loadDuration("JWT_REFRESH_TTL"),
+		Leeway:     loadDuration("JWT_LEEWAY"),
+	}
+
+	if cfg.SecretFile != "" {
+		data, err := os.ReadFile(cfg.SecretFile)
+		if err != nil {
+			return JWT{}, fmt.Errorf("reading JWT_SECRET_FILE: %w", err)
+		}
+		cfg.Secret = strings.TrimSpace(string(data))
+	}
+
+	if cfg.Secret == "" && cfg.KeysFile == "" {
+		return JWT{}, errors.New("no JWT signing key configured: set JWT_KEYS_FILE, JWT_SECRET or JWT_SECRET_FILE")
+	}
+	if cfg.Secret != "" && len(cfg.Secret) < token.MinHMACSecretLength {
+		return JWT{}, fmt.Errorf("JWT signing key must be at least %d bytes", token.MinHMACSecretLength)
+	}
+	if cfg.TTL <= 0 || cfg.RefreshTTL <= 0 {
+		return JWT{}, errors.New("JWT_TTL and JWT_REFRESH_TTL must be positive")
+	}
+	if cfg.Leeway < 0 {
+		return JWT{}, errors.New("JWT_LEEWAY must not be negative")
+	}
+	return cfg, nil
+}
+
+// loadOIDC requires an absolute issuer URL when the provider is enabled,
+// since relying parties fetch the discovery document from it.
+func loadOIDC(jwtCfg JWT) (OIDC, error) {
+	cfg := OIDC{
+		Enabled:    loadBool("OIDC_ENABLED"),
+		LoginURL:   loadString("OIDC_LOGIN_URL"),
+		RequestTTL: loadDuration("OIDC_REQUEST_TTL"),
+		CodeTTL:    loadDuration("OIDC_CODE_TTL"),
+	}
+	if !cfg.Enabled {
+		return cfg, nil
+	}
+
+	issuer, err := url.Parse(jwtCfg.Issuer)
+	if err != nil || (issuer.Scheme != "https" && issuer.Scheme != "http") || issuer.Host == "" {
+		return OIDC{}, errors.New("OIDC_ENABLED requires JWT_ISSUER to be the service's absolute URL")
+	}
+	if cfg.RequestTTL <= 0 || cfg.CodeTTL <= 0 {
+		return OIDC{}, errors.New("OIDC_REQUEST_TTL and OIDC_CODE_TTL must be positive")
+	}
+	return cfg, nil
+}
+
+type countryPolicyJSON struct {
+	Length     int      `json:"length"`
+	Expiry     string   `json:"expiry"`
+	Channels   []string `json:"channels"`
+	RateLimit  int      `json:"rate_limit"`
+	RateWindow string   `json:"rate_window"`
+}
+
+// loadCountryPolicies parses a JSON object keyed by ISO region, e.g.
+// {"IR": {"length": 5, "expiry": "3m", "channels": ["sms", "voice"]}}.
+func loadCountryPolicies(envName string) (map[string]CountryPolicy, error) {
+	raw := strings.TrimSpace(loadString(envName))
+	if raw == "" {
+		return nil, nil
+	}
+
+	var parsed map[string]countryPolicyJSON
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", envName, err)
+	}
+
+	policies := make(map[string]CountryPolicy, len(parsed))
+	for region, p := range parsed {
+		policy := CountryPolicy{
+			Length:    p.Length,
+			Channels:  p.Channels,
+			RateLimit: p.RateLimit,
+		}
+
+		var err error
+		if p.Expiry != "" {
+			if policy.Expiry, err = time.ParseDuration(p.Expiry); err != nil {
+				return nil, fmt.Errorf("parsing %s expiry for %s: %w", envName, region, err)
+			}
+		}
+		if p.RateWindow != "" {
+			if policy.RateWindow, err = time.ParseDuration(p.RateWindow); err != nil {
+				return nil, fmt.Errorf("parsing %s rate_window for %s: %w", envName, region, err)
+			}
+		}
+
+		policies[strings.ToUpper(region)] = policy
+	}
+	return policies, nil
+}
+
+func upper(values []string) []string {
+	for i, v := range values {
+		values[i] = strings.ToUpper(v)
+	}
+	return values
+}
@@ -0,0 +1,73 @@
+// Package enrich adds custom claims, such as a tenant ID, plan or feature
+// flags, to the access tokens of a user.
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"otp-auth-service/internal/model"
+	"time"
+)
+
+// Enricher returns extra claims for a user's access token. Claims are
+// namespaced under the ext claim, so they cannot shadow registered ones.
+type Enricher interface {
+	Enrich(ctx context.Context, user *model.User) (map[string]interface{}, error)
+}
+
+// chain runs enrichers in order and merges their claims, later ones
+// winning on conflicts. A failing or slow enricher is skipped so that
+// logins never depend on it.
+type chain struct {
+	enrichers []Enricher
+	timeout   time.Duration
+	maxSize   int
+}
+
+// NewChain combines enrichers. Each call gets at most timeout; claims that
+// would push the encoded ext claim past maxSize bytes are dropped.
+func NewChain(timeout time.Duration, maxSize int, enrichers ...Enricher) Enricher {
+	return &chain{enrichers: enrichers, timeout: timeout, maxSize: maxSize}
+}
+
+func (c *chain) Enrich(ctx context.Context, user *model.User) (map[string]interface{}, error) {
+	merged := map[string]interface{}{}
+	for _, enricher := range c.enrichers {
+		claims, err := c.call(ctx, enricher, user)
+		if err != nil {
+			log.Printf("claims enricher %T failed for user %d, skipping: %v", enricher, user.ID, err)
+			continue
+		}
+
+		candidate := make(map[string]interface{}, len(merged)+len(claims))
+		for key, value := range merged {
+			candidate[key] = value
+		}
+		for key, value := range claims {
+			candidate[key] = value
+		}
+
+		encoded, err := json.Marshal(candidate)
+		if err != nil {
+			log.Printf("claims enricher %T returned unencodable claims for user %d, skipping: %v", enricher, user.ID, err)
+			continue
+		}
+		if len(encoded) > c.maxSize {
+			log.Printf("claims enricher %T exceeded %d bytes for user %d, skipping", enricher, c.maxSize, user.ID)
+			continue
+		}
+		merged = candidate
+	}
+
+	if len(merged) == 0 {
+		return nil, nil
+	}
+	return merged, nil
+}
+
+func (c *chain) call(ctx context.Context, enricher Enricher, user *model.User) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return enricher.Enrich(ctx, user)
+}
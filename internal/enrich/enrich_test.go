@@ -0,0 +1,95 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"otp-auth-service/internal/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type staticEnricher map[string]interface{}
+
+func (e staticEnricher) Enrich(context.Context, *model.User) (map[string]interface{}, error) {
+	return e, nil
+}
+
+type failingEnricher struct{}
+
+func (failingEnricher) Enrich(context.Context, *model.User) (map[string]interface{}, error) {
+	return nil, errors.New("hook down")
+}
+
+func TestChainMergesLaterWins(t *testing.T) {
+	chain := NewChain(time.Second, 1024,
+		staticEnricher{"tenant": "acme", "plan": "free"},
+		failingEnricher{},
+		staticEnricher{"plan": "pro"},
+	)
+
+	claims, err := chain.Enrich(context.Background(), &model.User{ID: 7})
+	if err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	want := map[string]interface{}{"tenant": "acme", "plan": "pro"}
+	if !reflect.DeepEqual(claims, want) {
+		t.Errorf("claims = %v, want %v", claims, want)
+	}
+}
+
+func TestChainSkipsSlowHook(t *testing.T) {
+	release := make(chan struct{})
+	server := hookServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	defer close(release)
+
+	chain := NewChain(50*time.Millisecond, 1024,
+		staticEnricher{"tenant": "acme"},
+		NewHTTPEnricher(server.URL, "", 1024),
+	)
+
+	started := time.Now()
+	claims, err := chain.Enrich(context.Background(), &model.User{ID: 7})
+	if err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Enrich took %s, want it cut off at the timeout", elapsed)
+	}
+	want := map[string]interface{}{"tenant": "acme"}
+	if !reflect.DeepEqual(claims, want) {
+		t.Errorf("claims = %v, want %v", claims, want)
+	}
+}
+
+func TestChainDropsClaimsOverMaxSize(t *testing.T) {
+	// {"tenant":"acme"} is 17 bytes; adding the plan would make it 30
+	chain := NewChain(time.Second, 20,
+		staticEnricher{"tenant": "acme"},
+		staticEnricher{"plan": "pro"},
+	)
+
+	claims, err := chain.Enrich(context.Background(), &model.User{ID: 7})
+	if err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	want := map[string]interface{}{"tenant": "acme"}
+	if !reflect.DeepEqual(claims, want) {
+		t.Errorf("claims = %v, want %v", claims, want)
+	}
+}
+
+func TestChainNoClaims(t *testing.T) {
+	chain := NewChain(time.Second, 1024, failingEnricher{})
+
+	claims, err := chain.Enrich(context.Background(), &model.User{ID: 7})
+	if err != nil || claims != nil {
+		t.Errorf("Enrich = %v, %v; want nil, nil", claims, err)
+	}
+}
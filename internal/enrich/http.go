@@ -0,0 +1,85 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"otp-auth-service/internal/model"
+	"strconv"
+)
+
+// httpEnricher asks an external hook for claims. The hook receives the user
+// as JSON and answers with a JSON object of claims; any server on
+// localhost that does so works for development.
+type httpEnricher struct {
+	url     string
+	secret  string
+	maxSize int64
+	client  *http.Client
+}
+
+type hookRequest struct {
+	UserID      string   `json:"user_id"`
+	PhoneNumber string   `json:"phone_number"`
+	Roles       []string `json:"roles"`
+}
+
+// NewHTTPEnricher calls url with secret as a bearer token when set.
+// Responses larger than maxSize bytes are rejected. The caller's context
+// bounds each call.
+func NewHTTPEnricher(url, secret string, maxSize int) Enricher {
+	return &httpEnricher{
+		url:     url,
+		secret:  secret,
+		maxSize: int64(maxSize),
+		client:  &http.Client{},
+	}
+}
+
+func (e *httpEnricher) Enrich(ctx context.Context, user *model.User) (map[string]interface{}, error) {
+	body, err := json.Marshal(hookRequest{
+		UserID:      strconv.FormatUint(uint64(user.ID), 10),
+		PhoneNumber: user.PhoneNumber,
+		Roles:       user.RoleList(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.secret != "" {
+		req.Header.Set("Authorization", "Bearer "+e.secret)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling claims hook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("claims hook returned status %d", resp.StatusCode)
+	}
+
+	// Read one byte past the limit to tell a full response from a cut one
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, e.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading claims hook response: %w", err)
+	}
+	if int64(len(raw)) > e.maxSize {
+		return nil, fmt.Errorf("claims hook response exceeds %d bytes", e.maxSize)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return nil, fmt.Errorf("decoding claims hook response: %w", err)
+	}
+	return claims, nil
+}
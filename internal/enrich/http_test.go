@@ -0,0 +1,71 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"otp-auth-service/internal/model"
+	"strings"
+	"testing"
+)
+
+func hookServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPEnricher(t *testing.T) {
+	server := hookServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer hook-secret" {
+			t.Errorf("Authorization = %q, want Bearer hook-secret", got)
+		}
+		var req hookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding hook request: %v", err)
+		}
+		if req.UserID != "7" || req.PhoneNumber != "+15550100" || len(req.Roles) != 1 || req.Roles[0] != model.RoleAdmin {
+			t.Errorf("hook request = %+v", req)
+		}
+		w.Write([]byte(`{"tenant": "acme", "plan": "pro"}`))
+	})
+	enricher := NewHTTPEnricher(server.URL, "hook-secret", 1024)
+
+	user := &model.User{ID: 7, PhoneNumber: "+15550100", Roles: model.RoleAdmin}
+	claims, err := enricher.Enrich(context.Background(), user)
+	if err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	if claims["tenant"] != "acme" || claims["plan"] != "pro" {
+		t.Errorf("claims = %v", claims)
+	}
+}
+
+func TestHTTPEnricherRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"non-200", http.StatusInternalServerError, `{}`, "status 500"},
+		{"oversized body", http.StatusOK, `{"tenant": "` + strings.Repeat("a", 64) + `"}`, "exceeds 32 bytes"},
+		{"not an object", http.StatusOK, `["tenant"]`, "decoding"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := hookServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			enricher := NewHTTPEnricher(server.URL, "", 32)
+
+			claims, err := enricher.Enrich(context.Background(), &model.User{ID: 7})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Enrich = %v, %v; want error containing %q", claims, err, tt.wantErr)
+			}
+		})
+	}
+}
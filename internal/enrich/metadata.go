@@ -0,0 +1,31 @@
+package enrich
+
+import (
+	"context"
+	"otp-auth-service/internal/model"
+)
+
+// metadataEnricher copies selected keys of the user's metadata into the
+// token. Keys outside the list stay private to the service.
+type metadataEnricher struct {
+	keys []string
+}
+
+func NewMetadataEnricher(keys []string) Enricher {
+	return &metadataEnricher{keys: keys}
+}
+
+func (e *metadataEnricher) Enrich(_ context.Context, user *model.User) (map[string]interface{}, error) {
+	metadata, err := user.MetadataMap()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	for _, key := range e.keys {
+		if value, ok := metadata[key]; ok {
+			claims[key] = value
+		}
+	}
+	return claims, nil
+}
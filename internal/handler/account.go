@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AccountHandler struct {
+	authService    service.AuthService
+	accountService service.AccountService
+	accountCfg     config.Account
+	cookies        sessionCookies
+}
+
+func NewAccountHandler(authService service.AuthService, accountService service.AccountService, accountCfg config.Account, cookieCfg config.Cookie, jwtCfg config.JWT) *AccountHandler {
+	return &AccountHandler{
+		authService:    authService,
+		accountService: accountService,
+		accountCfg:     accountCfg,
+		cookies:        newSessionCookies(cookieCfg, jwtCfg),
+	}
+}
+
+type DeleteAccountRequest struct {
+	OTP string `json:"otp" binding:"required"`
+}
+
+// RequestDeletionOTP godoc
+// @Summary Request an account deletion OTP
+// @Description Send an OTP to the current user's phone number that confirms DELETE /me
+// @Tags users
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /me/delete/request-otp [post]
+func (h *AccountHandler) RequestDeletionOTP(c *gin.Context) {
+	userID, _ := middleware.MustGetClaims(c).UserID()
+
+	if err := h.authService.StartAccountDeletion(userID); err != nil {
+		if writeOTPRequestError(c, err) {
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate OTP"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "OTP sent successfully"})
+}
+
+// DeleteMe godoc
+// @Summary Delete the current user's account
+// @Description Confirm account deletion with the OTP from /me/delete/request-otp. The user is signed out everywhere and can no longer log in. The account, its sessions and devices are purged once ACCOUNT_DELETION_GRACE has passed, and the numbers are stripped from the OTP request history.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body DeleteAccountRequest true "Account deletion OTP"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /me [delete]
+func (h *AccountHandler) DeleteMe(c *gin.Context) {
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := middleware.MustGetClaims(c).UserID()
+
+	if err := h.authService.DeleteAccount(userID, req.OTP); err != nil {
+		if errors.Is(err, service.ErrInvalidOTP) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid OTP"})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	if middleware.CookieSession(c) {
+		h.cookies.clear(c)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Account deleted",
+		"purge_after": time.Now().UTC().Add(h.accountCfg.DeletionGrace),
+	})
+}
+
+// ExportMe godoc
+// @Summary Export the current user's data
+// @Description Download everything stored about the current user: profile, admin-managed attributes, phone number history, sessions, trusted devices and OTP requests. format=zip returns a ZIP archive with one JSON file per section.
+// @Tags users
+// @Produce json
+// @Produce application/zip
+// @Param format query string false "json (default) or zip"
+// @Success 200 {object} model.AccountExport
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /me/export [get]
+func (h *AccountHandler) ExportMe(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or zip"})
+		return
+	}
+
+	userID, _ := middleware.MustGetClaims(c).UserID()
+
+	export, err := h.accountService.Export(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export account"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	filename := fmt.Sprintf("account-%d-%s", userID, export.ExportedAt.Format("20060102T150405Z"))
+	if format == "json" {
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	archive, err := exportArchive(export)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export account"})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// exportArchive packs each section of the export into its own JSON file.
+func exportArchive(export *model.AccountExport) ([]byte, error) {
+	sections := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"attributes.json", export.Attributes},
+		{"phone_number_history.json", export.PhoneNumberHistory},
+		{"sessions.json", export.Sessions},
+		{"trusted_devices.json", export.TrustedDevices},
+		{"otp_requests.json", export.OTPRequests},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, section := range sections {
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     section.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(section.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -2,7 +2,12 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/phone"
 	"otp-auth-service/internal/service"
 	"strings"
 
@@ -11,28 +16,61 @@ import (
 
 type AuthHandler struct {
 	authService service.AuthService
+	dpop        service.DPoPService
+	cookies     sessionCookies
 }
 
-func NewAuthHandler(authService service.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService service.AuthService, dpop service.DPoPService, cookieCfg config.Cookie, jwtCfg config.JWT) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+		dpop:        dpop,
+		cookies:     newSessionCookies(cookieCfg, jwtCfg),
+	}
 }
 
-// normalizePhoneNumber ensures phone number has + prefix
-func normalizePhoneNumber(phone string) string {
-	phone = strings.TrimSpace(phone)
-	if !strings.HasPrefix(phone, "+") {
-		phone = "+" + phone
+// phoneErrorMessage maps phone number parsing errors to a client facing
+// message. ok is false for any other error.
+func phoneErrorMessage(err error) (message string, ok bool) {
+	switch {
+	case errors.Is(err, phone.ErrNotMobile):
+		return "Phone number must be a mobile number", true
+	case errors.Is(err, phone.ErrInvalidNumber):
+		return "Invalid phone number", true
 	}
-	return phone
+	return "", false
 }
 
 type RequestOTPRequest struct {
-	PhoneNumber string `json:"phone_number" binding:"required"`
+	PhoneNumber       string `json:"phone_number" binding:"required"`
+	ChallengeResponse string `json:"challenge_response"`
 }
 
 type VerifyOTPRequest struct {
 	PhoneNumber string `json:"phone_number" binding:"required"`
 	OTP         string `json:"otp" binding:"required"`
+	DeviceName  string `json:"device_name"`
+	// TrustDevice asks for a device token that logs in without an OTP.
+	TrustDevice bool `json:"trust_device"`
+	// SessionMode "cookie" starts a browser session; tokens are returned in
+	// the body otherwise.
+	SessionMode string `json:"session_mode"`
+}
+
+type DeviceLoginRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+	DeviceToken string `json:"device_token" binding:"required"`
+	DeviceName  string `json:"device_name"`
+	SessionMode string `json:"session_mode"`
+}
+
+// RefreshRequest carries the refresh token of a bearer session. Browser
+// sessions send theirs as a cookie instead.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 // RequestOTP godoc
@@ -44,6 +82,8 @@ type VerifyOTPRequest struct {
 // @Param request body RequestOTPRequest true "Phone number"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 428 {object} map[string]interface{}
 // @Failure 429 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /auth/request-otp [post]
@@ -54,10 +94,9 @@ func (h *AuthHandler) RequestOTP(c *gin.Context) {
 		return
 	}
 
-	err := h.authService.RequestOTP(req.PhoneNumber)
+	err := h.authService.RequestOTP(req.PhoneNumber, c.ClientIP(), req.ChallengeResponse)
 	if err != nil {
-		if err.Error() == "rate limit exceeded" {
-			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+		if writeOTPRequestError(c, err) {
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate OTP"})
@@ -67,16 +106,69 @@ func (h *AuthHandler) RequestOTP(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "OTP sent successfully"})
 }
 
+// writeOTPRequestError writes the response for errors shared by every
+// endpoint that sends an OTP. It reports false when err is not one of them.
+func writeOTPRequestError(c *gin.Context, err error) bool {
+	if message, ok := phoneErrorMessage(err); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return true
+	}
+	if writeAccountStatusError(c, err) {
+		return true
+	}
+
+	var challengeErr *service.ChallengeRequiredError
+	switch {
+	case errors.Is(err, service.ErrRateLimitExceeded):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+	case errors.Is(err, service.ErrPhoneBlocked):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Phone number is blocked"})
+	case errors.Is(err, service.ErrCountryNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": "OTP is not available in this country"})
+	case errors.As(err, &challengeErr):
+		message := "Challenge required"
+		if challengeErr.Failed {
+			message = "Challenge verification failed"
+		}
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"error":     message,
+			"code":      "challenge_required",
+			"challenge": challengeErr.Challenge,
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+// writeAccountStatusError answers 403 for accounts that may not log in. It
+// reports false when err is not about the account status.
+func writeAccountStatusError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrAccountDeleted):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account has been deleted", "code": "account_deleted"})
+	case errors.Is(err, service.ErrAccountSuspended):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended", "code": "account_suspended"})
+	case errors.Is(err, service.ErrAccountBanned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is banned", "code": "account_banned"})
+	default:
+		return false
+	}
+	return true
+}
+
 // VerifyOTP godoc
 // @Summary Verify OTP and login/register
-// @Description Verify OTP and return JWT token
+// @Description Verify OTP and return an access token and a refresh token. With trust_device the response also carries a device_token for /auth/device-login. With session_mode "cookie" the tokens are set as HttpOnly cookies and the response carries the CSRF token to send in X-CSRF-Token. A DPoP proof header binds the session's access tokens to the proof key.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body VerifyOTPRequest true "Phone number and OTP"
-// @Success 200 {object} map[string]string
+// @Param DPoP header string false "DPoP proof"
+// @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /auth/verify-otp [post]
 func (h *AuthHandler) VerifyOTP(c *gin.Context) {
@@ -86,11 +178,203 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 		return
 	}
 
-	token, err := h.authService.VerifyOTP(req.PhoneNumber, req.OTP)
+	dpopJKT, ok := dpopBinding(c, h.dpop)
+	if !ok {
+		return
+	}
+	client := sessionInfo(c, req.DeviceName)
+	client.DPoPJKT = dpopJKT
+
+	tokens, err := h.authService.VerifyOTP(req.PhoneNumber, req.OTP, client, req.TrustDevice)
 	if err != nil {
+		if message, ok := phoneErrorMessage(err); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+		if writeAccountStatusError(c, err) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid OTP"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	h.cookies.respond(c, tokens, req.SessionMode == sessionModeCookie)
+}
+
+// DeviceLogin godoc
+// @Summary Log in from a trusted device
+// @Description Log in without an OTP using the device token returned by a verify-otp call with trust_device
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body DeviceLoginRequest true "Phone number and device token"
+// @Param DPoP header string false "DPoP proof"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/device-login [post]
+func (h *AuthHandler) DeviceLogin(c *gin.Context) {
+	var req DeviceLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	dpopJKT, ok := dpopBinding(c, h.dpop)
+	if !ok {
+		return
+	}
+	client := sessionInfo(c, req.DeviceName)
+	client.DPoPJKT = dpopJKT
+
+	tokens, err := h.authService.DeviceLogin(req.PhoneNumber, req.DeviceToken, client)
+	if err != nil {
+		if message, ok := phoneErrorMessage(err); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+		if writeAccountStatusError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrInvalidDeviceToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid device token"})
+		case errors.Is(err, service.ErrPhoneBlocked):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Phone number is blocked"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		}
+		return
+	}
+
+	h.cookies.respond(c, tokens, req.SessionMode == sessionModeCookie)
+}
+
+// Refresh godoc
+// @Summary Refresh the access token
+// @Description Exchange a refresh token for a new access token and a rotated refresh token. Reusing a rotated refresh token revokes its whole family. Browser sessions omit the body and send the refresh token cookie and X-CSRF-Token instead. DPoP-bound sessions need a proof from the same key.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest false "Refresh token"
+// @Param DPoP header string false "DPoP proof"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+
+	cookieMode := false
+	if req.RefreshToken == "" {
+		cookie, err := c.Cookie(middleware.RefreshTokenCookie)
+		if err != nil || cookie == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		if !middleware.ValidCSRF(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "CSRF token missing or invalid"})
+			return
+		}
+		req.RefreshToken = cookie
+		cookieMode = true
+	}
+
+	dpopJKT, ok := dpopBinding(c, h.dpop)
+	if !ok {
+		return
+	}
+	client := sessionInfo(c, "")
+	client.DPoPJKT = dpopJKT
+
+	tokens, err := h.authService.Refresh(req.RefreshToken, client)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		if writeAccountStatusError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	h.cookies.respond(c, tokens, cookieMode)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Revoke the current access token and end its session, along with the session of the given refresh token. Browser sessions also have their cookies cleared.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body LogoutRequest false "Refresh token to revoke"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+
+	claims := middleware.MustGetClaims(c)
+	userID, _ := claims.UserID()
+
+	if req.RefreshToken == "" && middleware.CookieSession(c) {
+		req.RefreshToken, _ = c.Cookie(middleware.RefreshTokenCookie)
+	}
+
+	if err := h.authService.Logout(userID, claims.SessionID, claims.ID, claims.ExpiresAt.Time, req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	if middleware.CookieSession(c) {
+		h.cookies.clear(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// sessionInfo describes the client of the current request for the session
+// record.
+func sessionInfo(c *gin.Context, deviceName string) model.SessionInfo {
+	return model.SessionInfo{
+		DeviceName: strings.TrimSpace(deviceName),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+}
+
+// tokenResponse renders a token pair. "token" duplicates the access token
+// for clients written before refresh tokens existed.
+func tokenResponse(tokens *model.TokenPair) gin.H {
+	response := gin.H{
+		"token":         tokens.AccessToken,
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"token_type":    tokens.TokenType,
+		"expires_in":    tokens.ExpiresIn,
+	}
+	if tokens.DeviceToken != "" {
+		response["device_token"] = tokens.DeviceToken
+	}
+	return response
 }
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/model"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionModeCookie asks login endpoints for a browser session: tokens go
+// into HttpOnly cookies and never reach scripts.
+const sessionModeCookie = "cookie"
+
+// refreshCookiePath limits the refresh token cookie to the endpoints that
+// use it.
+const refreshCookiePath = "/auth"
+
+// sessionCookies writes and clears the cookies of browser sessions.
+type sessionCookies struct {
+	cfg        config.Cookie
+	refreshTTL time.Duration
+}
+
+func newSessionCookies(cookieCfg config.Cookie, jwtCfg config.JWT) sessionCookies {
+	return sessionCookies{cfg: cookieCfg, refreshTTL: jwtCfg.RefreshTTL}
+}
+
+// respond renders tokens as JSON, or as cookies when cookieMode is set.
+func (s sessionCookies) respond(c *gin.Context, tokens *model.TokenPair, cookieMode bool) {
+	if !cookieMode {
+		c.JSON(http.StatusOK, tokenResponse(tokens))
+		return
+	}
+
+	csrfToken, err := newCSRFToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+		return
+	}
+
+	s.set(c, middleware.AccessTokenCookie, tokens.AccessToken, "/", tokens.ExpiresIn, true)
+	s.set(c, middleware.RefreshTokenCookie, tokens.RefreshToken, refreshCookiePath, int(s.refreshTTL.Seconds()), true)
+	// The CSRF cookie must outlive the access token so refreshes can send it
+	s.set(c, middleware.CSRFCookie, csrfToken, "/", int(s.refreshTTL.Seconds()), false)
+
+	response := gin.H{
+		"csrf_token": csrfToken,
+		"expires_in": tokens.ExpiresIn,
+	}
+	if tokens.DeviceToken != "" {
+		response["device_token"] = tokens.DeviceToken
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// respondAccess renders a new access token for an existing session. Browser
+// sessions keep their refresh and CSRF cookies.
+func (s sessionCookies) respondAccess(c *gin.Context, tokens *model.TokenPair, cookieMode bool) {
+	if !cookieMode {
+		c.JSON(http.StatusOK, gin.H{
+			"access_token": tokens.AccessToken,
+			"token_type":   tokens.TokenType,
+			"expires_in":   tokens.ExpiresIn,
+		})
+		return
+	}
+
+	s.set(c, middleware.AccessTokenCookie, tokens.AccessToken, "/", tokens.ExpiresIn, true)
+	c.JSON(http.StatusOK, gin.H{"expires_in": tokens.ExpiresIn})
+}
+
+// clear expires every browser session cookie.
+func (s sessionCookies) clear(c *gin.Context) {
+	s.set(c, middleware.AccessTokenCookie, "", "/", -1, true)
+	s.set(c, middleware.RefreshTokenCookie, "", refreshCookiePath, -1, true)
+	s.set(c, middleware.CSRFCookie, "", "/", -1, false)
+}
+
+func (s sessionCookies) set(c *gin.Context, name, value, path string, maxAge int, httpOnly bool) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   s.cfg.Domain,
+		MaxAge:   maxAge,
+		Secure:   s.cfg.Secure,
+		HttpOnly: httpOnly,
+		SameSite: s.sameSite(),
+	})
+}
+
+func (s sessionCookies) sameSite() http.SameSite {
+	switch s.cfg.SameSite {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+func newCSRFToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
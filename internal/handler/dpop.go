@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dpopBinding verifies the DPoP proof sent to a login or refresh endpoint,
+// if any, and returns the key thumbprint to bind the new tokens to. It
+// writes the error response and reports false when the proof is rejected.
+func dpopBinding(c *gin.Context, dpop service.DPoPService) (string, bool) {
+	proof := c.GetHeader(middleware.DPoPHeader)
+	if proof == "" {
+		return "", true
+	}
+
+	jkt, err := dpop.VerifyProof(proof, c.Request.Method, middleware.RequestURL(c), "")
+	switch {
+	case errors.Is(err, service.ErrUseDPoPNonce):
+		middleware.SetDPoPNonce(c, dpop)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "DPoP nonce required", "code": "use_dpop_nonce"})
+	case errors.Is(err, service.ErrInvalidDPoPProof):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DPoP proof", "code": "invalid_dpop_proof"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify DPoP proof"})
+	default:
+		return jkt, true
+	}
+	return "", false
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"otp-auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IntrospectionHandler struct {
+	introspectionService service.IntrospectionService
+}
+
+func NewIntrospectionHandler(introspectionService service.IntrospectionService) *IntrospectionHandler {
+	return &IntrospectionHandler{introspectionService: introspectionService}
+}
+
+// Introspect godoc
+// @Summary Token introspection
+// @Description RFC 7662 introspection of access and refresh tokens for confidential OAuth clients. Revoked, expired and unknown tokens are reported as inactive.
+// @Tags oidc
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Token to introspect"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret"
+// @Success 200 {object} service.IntrospectionResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /oauth/introspect [post]
+func (h *IntrospectionHandler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	clientID, clientSecret, ok := clientCredentials(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.OAuthInvalidRequest, "error_description": "malformed client credentials"})
+		return
+	}
+
+	rawToken := c.PostForm("token")
+	if rawToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.OAuthInvalidRequest, "error_description": "token is required"})
+		return
+	}
+
+	response, err := h.introspectionService.Introspect(clientID, clientSecret, rawToken, c.PostForm("token_type_hint"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidClient) {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": service.OAuthInvalidClient, "error_description": "client authentication failed"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+	"otp-auth-service/internal/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	keys *token.KeySet
+}
+
+func NewJWKSHandler(keys *token.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// GetJWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys used to verify tokens issued by this service
+// @Tags auth
+// @Produce json
+// @Success 200 {object} token.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"otp-auth-service/internal/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthClientHandler struct {
+	clientService service.OAuthClientService
+}
+
+func NewOAuthClientHandler(clientService service.OAuthClientService) *OAuthClientHandler {
+	return &OAuthClientHandler{clientService: clientService}
+}
+
+type CreateOAuthClientRequest struct {
+	Name         string     `json:"name" binding:"required"`
+	RedirectURIs []string   `json:"redirect_uris"`
+	Scopes       []string   `json:"scopes" binding:"required"`
+	GrantTypes   []string   `json:"grant_types"`
+	Public       bool       `json:"public"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+}
+
+// ListOAuthClients godoc
+// @Summary List OAuth clients
+// @Description List registered OAuth/OpenID Connect clients, including revoked ones
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/oauth-clients [get]
+func (h *OAuthClientHandler) ListOAuthClients(c *gin.Context) {
+	clients, err := h.clientService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch OAuth clients"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"clients": clients})
+}
+
+// CreateOAuthClient godoc
+// @Summary Register an OAuth client
+// @Description Register a relying party or, with the client_credentials grant, a service client whose secret also works as an X-API-Key. The client secret is only returned in this response; public clients get none.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body CreateOAuthClientRequest true "Client"
+// @Success 201 {object} model.OAuthClientResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/oauth-clients [post]
+func (h *OAuthClientHandler) CreateOAuthClient(c *gin.Context) {
+	var req CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	client, err := h.clientService.Create(service.ClientParams{
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		GrantTypes:   req.GrantTypes,
+		Public:       req.Public,
+		ExpiresAt:    req.ExpiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidClientConfig) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create OAuth client"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, client)
+}
+
+// RotateOAuthClientSecret godoc
+// @Summary Rotate an OAuth client secret
+// @Description Replace the secret of a confidential client. The old secret stops working immediately; the new one is only returned in this response.
+// @Tags admin
+// @Produce json
+// @Param client_id path string true "Client ID"
+// @Success 200 {object} model.OAuthClientResponse
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/oauth-clients/{client_id}/rotate-secret [post]
+func (h *OAuthClientHandler) RotateOAuthClientSecret(c *gin.Context) {
+	client, err := h.clientService.RotateSecret(c.Param("client_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrClientNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "OAuth client not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate client secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
+
+// RevokeOAuthClient godoc
+// @Summary Revoke an OAuth client
+// @Description Disable the client; access tokens issued to it stop working
+// @Tags admin
+// @Produce json
+// @Param client_id path string true "Client ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/oauth-clients/{client_id} [delete]
+func (h *OAuthClientHandler) RevokeOAuthClient(c *gin.Context) {
+	if err := h.clientService.Revoke(c.Param("client_id")); err != nil {
+		if errors.Is(err, service.ErrClientNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "OAuth client not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke OAuth client"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCHandler struct {
+	oidcService service.OIDCService
+	oidcCfg     config.OIDC
+}
+
+func NewOIDCHandler(oidcService service.OIDCService, oidcCfg config.OIDC) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService, oidcCfg: oidcCfg}
+}
+
+type CompleteAuthorizationRequest struct {
+	RequestID   string `json:"request_id" binding:"required"`
+	PhoneNumber string `json:"phone_number" binding:"required"`
+	OTP         string `json:"otp" binding:"required"`
+	DeviceName  string `json:"device_name"`
+}
+
+// Discovery godoc
+// @Summary OpenID Connect discovery document
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} service.DiscoveryDocument
+// @Router /.well-known/openid-configuration [get]
+func (h *OIDCHandler) Discovery(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.oidcService.Discovery())
+}
+
+// Authorize godoc
+// @Summary Start an authorization code flow
+// @Description Validate an authorization request (PKCE with S256 is required) and hand it to the login page, which completes it after an OTP login. Without a configured login page the request is returned as JSON.
+// @Tags oidc
+// @Produce json
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param scope query string true "Space separated scopes including openid"
+// @Param state query string false "Opaque client state"
+// @Param nonce query string false "ID token nonce"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Success 200 {object} map[string]interface{}
+// @Success 302
+// @Failure 400 {object} map[string]string
+// @Router /oauth/authorize [get]
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	request, err := h.oidcService.Authorize(service.AuthorizeParams{
+		ResponseType:        c.Query("response_type"),
+		ClientID:            c.Query("client_id"),
+		RedirectURI:         c.Query("redirect_uri"),
+		Scope:               c.Query("scope"),
+		State:               c.Query("state"),
+		Nonce:               c.Query("nonce"),
+		CodeChallenge:       c.Query("code_challenge"),
+		CodeChallengeMethod: c.Query("code_challenge_method"),
+	})
+	if err != nil {
+		var redirectErr *service.RedirectError
+		var oauthErr *service.OAuthError
+		switch {
+		case errors.As(err, &redirectErr):
+			c.Redirect(http.StatusFound, redirectErr.Location(h.oidcService.Issuer()))
+		case errors.As(err, &oauthErr):
+			c.JSON(http.StatusBadRequest, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start authorization"})
+		}
+		return
+	}
+
+	if h.oidcCfg.LoginURL != "" {
+		loginURL, err := url.Parse(h.oidcCfg.LoginURL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid login URL"})
+			return
+		}
+		query := loginURL.Query()
+		query.Set("request_id", request.ID)
+		loginURL.RawQuery = query.Encode()
+		c.Redirect(http.StatusFound, loginURL.String())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"request_id": request.ID,
+		"client_id":  request.ClientID,
+		"scope":      request.Scope,
+		"expires_at": request.ExpiresAt,
+	})
+}
+
+// CompleteAuthorization godoc
+// @Summary Complete an authorization request
+// @Description Log in with an OTP sent via /auth/request-otp and get the client redirect URI carrying the authorization code
+// @Tags oidc
+// @Accept json
+// @Produce json
+// @Param request body CompleteAuthorizationRequest true "Authorization request and OTP"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /oauth/authorize/complete [post]
+func (h *OIDCHandler) CompleteAuthorization(c *gin.Context) {
+	var req CompleteAuthorizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	redirectTo, err := h.oidcService.CompleteAuthorization(req.RequestID, req.PhoneNumber, req.OTP, sessionInfo(c, req.DeviceName))
+	if err != nil {
+		if message, ok := phoneErrorMessage(err); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+		if writeAccountStatusError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrAuthorizationRequestNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Authorization request not found or expired"})
+		case errors.Is(err, service.ErrInvalidOTP):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid OTP"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete authorization"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"redirect_to": redirectTo})
+}
+
+// Token godoc
+// @Summary OAuth token endpoint
+// @Description Redeem an authorization code for an access token and an ID token, get a service token with the client_credentials grant, or exchange a user's access token for a downscoped token addressed to another service (RFC 8693). Clients authenticate with HTTP Basic or client_secret_post; public clients send only client_id.
+// @Tags oidc
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code, client_credentials or urn:ietf:params:oauth:grant-type:token-exchange"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI of the authorization request"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param scope formData string false "Requested service scopes for client_credentials, or a subset of the subject token's scopes for token exchange"
+// @Param subject_token formData string false "Access token to exchange"
+// @Param subject_token_type formData string false "urn:ietf:params:oauth:token-type:access_token"
+// @Param requested_token_type formData string false "urn:ietf:params:oauth:token-type:access_token"
+// @Param audience formData string false "Service the exchanged token is for"
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret"
+// @Success 200 {object} service.OIDCTokenResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /oauth/token [post]
+func (h *OIDCHandler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	clientID, clientSecret, ok := clientCredentials(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.OAuthInvalidRequest, "error_description": "malformed client credentials"})
+		return
+	}
+
+	tokens, err := h.oidcService.Token(service.TokenParams{
+		GrantType:    c.PostForm("grant_type"),
+		Code:         c.PostForm("code"),
+		RedirectURI:  c.PostForm("redirect_uri"),
+		CodeVerifier: c.PostForm("code_verifier"),
+		Scope:        c.PostForm("scope"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+
+		SubjectToken:       c.PostForm("subject_token"),
+		SubjectTokenType:   c.PostForm("subject_token_type"),
+		RequestedTokenType: c.PostForm("requested_token_type"),
+		Audience:           c.PostForm("audience"),
+	})
+	if err != nil {
+		writeOAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// UserInfo godoc
+// @Summary OpenID Connect userinfo
+// @Description Claims about the user the access token was issued for, limited by its scope
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /oauth/userinfo [get]
+func (h *OIDCHandler) UserInfo(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+	userID, _ := claims.UserID()
+
+	info, err := h.oidcService.UserInfo(userID, claims.Scope)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// clientCredentials reads client credentials from HTTP Basic auth or the
+// form body. ok is false when both are used or Basic auth is malformed.
+func clientCredentials(c *gin.Context) (clientID, clientSecret string, ok bool) {
+	if c.GetHeader("Authorization") == "" {
+		return c.PostForm("client_id"), c.PostForm("client_secret"), true
+	}
+
+	username, password, hasBasic := c.Request.BasicAuth()
+	if !hasBasic || c.PostForm("client_secret") != "" {
+		return "", "", false
+	}
+	// RFC 6749 form-encodes the credentials before Basic encoding
+	clientID, err := url.QueryUnescape(username)
+	if err != nil {
+		return "", "", false
+	}
+	clientSecret, err = url.QueryUnescape(password)
+	if err != nil {
+		return "", "", false
+	}
+	return clientID, clientSecret, true
+}
+
+// writeOAuthError renders err as an RFC 6749 error response.
+func writeOAuthError(c *gin.Context, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == service.OAuthInvalidClient {
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	c.JSON(status, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"net/http"
+	"otp-auth-service/internal/phone"
+	"otp-auth-service/internal/repository"
+	"otp-auth-service/internal/service"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OTPAdminHandler struct {
+	otpAdminService service.OTPAdminService
+	auditRepo       repository.AuditRepository
+	phoneParser     *phone.Parser
+}
+
+func NewOTPAdminHandler(otpAdminService service.OTPAdminService, auditRepo repository.AuditRepository, phoneParser *phone.Parser) *OTPAdminHandler {
+	return &OTPAdminHandler{otpAdminService: otpAdminService, auditRepo: auditRepo, phoneParser: phoneParser}
+}
+
+type ResetOTPStateRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+	Reason      string `json:"reason" binding:"required"`
+	ClearOTP    bool   `json:"clear_otp"`
+}
+
+// GetOTPState godoc
+// @Summary Inspect OTP rate limit state
+// @Description Show counters, remaining quota and lockout status for a phone number, plus the active OTP TTL and wrong guesses for each OTP purpose (login, phone_change, reauth, account_deletion)
+// @Tags admin
+// @Produce json
+// @Param phone query string true "Phone number"
+// @Success 200 {object} model.PhoneOTPState
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/otp/state [get]
+func (h *OTPAdminHandler) GetOTPState(c *gin.Context) {
+	if strings.TrimSpace(c.Query("phone")) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Phone number is required"})
+		return
+	}
+	number, err := h.phoneParser.Parse(c.Query("phone"))
+	if err != nil {
+		message, _ := phoneErrorMessage(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	state, err := h.otpAdminService.GetPhoneState(number)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get OTP state"})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// ResetOTPState godoc
+// @Summary Reset OTP rate limit state
+// @Description Reset the rate limit counters and the wrong-guess counters of every OTP purpose for a phone number. clear_otp also withdraws the active OTPs. The reason is written to the audit log
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body ResetOTPStateRequest true "Phone number and reason"
+// @Success 200 {object} model.PhoneOTPState
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/otp/reset [post]
+func (h *OTPAdminHandler) ResetOTPState(c *gin.Context) {
+	var req ResetOTPStateRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	number, err := h.phoneParser.Parse(req.PhoneNumber)
+	if err != nil {
+		message, _ := phoneErrorMessage(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	state, err := h.otpAdminService.ResetPhoneState(number, adminActor(c), strings.TrimSpace(req.Reason), req.ClearOTP)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset OTP state"})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// GetAuditLogs godoc
+// @Summary List audit log entries
+// @Tags admin
+// @Produce json
+// @Param target query string false "Filter by target"
+// @Param limit query int false "Maximum number of entries" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/audit-logs [get]
+func (h *OTPAdminHandler) GetAuditLogs(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	target := strings.TrimSpace(c.Query("target"))
+	entries, err := h.auditRepo.FindByTarget(target, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// adminActor names the operator for audit entries, taken from the
+// X-Admin-Actor header.
+func adminActor(c *gin.Context) string {
+	if actor := strings.TrimSpace(c.GetHeader("X-Admin-Actor")); actor != "" {
+		return actor
+	}
+	return "admin"
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"otp-auth-service/internal/phone"
 	"otp-auth-service/internal/repository"
 	"strconv"
 	"strings"
@@ -11,11 +12,12 @@ import (
 )
 
 type OTPStatsHandler struct {
-	otpRepo repository.OTPRepository
+	otpRepo     repository.OTPRepository
+	phoneParser *phone.Parser
 }
 
-func NewOTPStatsHandler(otpRepo repository.OTPRepository) *OTPStatsHandler {
-	return &OTPStatsHandler{otpRepo: otpRepo}
+func NewOTPStatsHandler(otpRepo repository.OTPRepository, phoneParser *phone.Parser) *OTPStatsHandler {
+	return &OTPStatsHandler{otpRepo: otpRepo, phoneParser: phoneParser}
 }
 
 // GetOTPStats godoc
@@ -31,16 +33,19 @@ func NewOTPStatsHandler(otpRepo repository.OTPRepository) *OTPStatsHandler {
 // @Failure 500 {object} map[string]string
 // @Router /otp/stats [get]
 func (h *OTPStatsHandler) GetOTPStats(c *gin.Context) {
-	phoneNumber := strings.TrimSpace(c.Query("phone"))
-	if phoneNumber == "" {
+	if strings.TrimSpace(c.Query("phone")) == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Phone number is required"})
 		return
 	}
 
-	// Ensure phone number has + prefix
-	if !strings.HasPrefix(phoneNumber, "+") {
-		phoneNumber = "+" + phoneNumber
+	// Look up the canonical E.164 form the requests were recorded under
+	number, err := h.phoneParser.Parse(c.Query("phone"))
+	if err != nil {
+		message, _ := phoneErrorMessage(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
 	}
+	phoneNumber := number.E164
 
 	hoursStr := c.DefaultQuery("hours", "24")
 	hours, err := strconv.Atoi(hoursStr)
@@ -73,6 +78,7 @@ func (h *OTPStatsHandler) GetOTPStats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"phone_number":        phoneNumber,
+		"country":             number.Region,
 		"hours_looked_back":   hours,
 		"total_requests":      totalCount,
 		"successful_requests": successfulCount,
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PhoneChangeHandler struct {
+	authService service.AuthService
+	cookies     sessionCookies
+}
+
+func NewPhoneChangeHandler(authService service.AuthService, cookieCfg config.Cookie, jwtCfg config.JWT) *PhoneChangeHandler {
+	return &PhoneChangeHandler{
+		authService: authService,
+		cookies:     newSessionCookies(cookieCfg, jwtCfg),
+	}
+}
+
+type StartPhoneChangeRequest struct {
+	NewPhoneNumber    string `json:"new_phone_number" binding:"required"`
+	ChallengeResponse string `json:"challenge_response"`
+}
+
+type ConfirmPhoneChangeRequest struct {
+	NewPhoneNumber string `json:"new_phone_number" binding:"required"`
+	OTP            string `json:"otp" binding:"required"`
+	OldOTP         string `json:"old_otp"`
+	DeviceName     string `json:"device_name"`
+}
+
+// StartPhoneChange godoc
+// @Summary Start changing the phone number
+// @Description Send a phone-change OTP to the new number (and to the current number when required)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body StartPhoneChangeRequest true "New phone number"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 428 {object} map[string]interface{}
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /me/phone/change [post]
+func (h *PhoneChangeHandler) StartPhoneChange(c *gin.Context) {
+	var req StartPhoneChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := middleware.MustGetClaims(c).UserID()
+	err := h.authService.StartPhoneChange(userID, req.NewPhoneNumber, c.ClientIP(), req.ChallengeResponse)
+	if err != nil {
+		if writeOTPRequestError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrSamePhoneNumber):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "New phone number is the current one"})
+		case errors.Is(err, service.ErrPhoneNumberTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": "Phone number is already in use"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start phone change"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "OTP sent successfully"})
+}
+
+// ConfirmPhoneChange godoc
+// @Summary Confirm the phone number change
+// @Description Verify the phone-change OTPs, switch to the new number and revoke existing tokens
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body ConfirmPhoneChangeRequest true "New phone number and OTPs"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /me/phone/change/confirm [post]
+func (h *PhoneChangeHandler) ConfirmPhoneChange(c *gin.Context) {
+	var req ConfirmPhoneChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	claims := middleware.MustGetClaims(c)
+	userID, _ := claims.UserID()
+
+	// The new session stays bound to the DPoP key of the current one
+	client := sessionInfo(c, req.DeviceName)
+	if claims.Confirmation != nil {
+		client.DPoPJKT = claims.Confirmation.JKT
+	}
+
+	tokens, err := h.authService.ConfirmPhoneChange(userID, req.NewPhoneNumber, req.OTP, req.OldOTP, client)
+	if err != nil {
+		if message, ok := phoneErrorMessage(err); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrInvalidOTP):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid OTP"})
+		case errors.Is(err, service.ErrPhoneNumberTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": "Phone number is already in use"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change phone number"})
+		}
+		return
+	}
+
+	// Browser sessions get their new tokens as cookies again
+	h.cookies.respond(c, tokens, middleware.CookieSession(c))
+}
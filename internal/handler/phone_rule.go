@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"otp-auth-service/internal/service"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PhoneRuleHandler struct {
+	phoneRuleService service.PhoneRuleService
+}
+
+func NewPhoneRuleHandler(phoneRuleService service.PhoneRuleService) *PhoneRuleHandler {
+	return &PhoneRuleHandler{phoneRuleService: phoneRuleService}
+}
+
+type CreatePhoneRuleRequest struct {
+	List      string `json:"list" binding:"required"`
+	MatchType string `json:"match_type" binding:"required"`
+	Value     string `json:"value" binding:"required"`
+	Note      string `json:"note"`
+}
+
+// ListPhoneRules godoc
+// @Summary List phone rules
+// @Description List allow, deny and test phone number rules
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/phone-rules [get]
+func (h *PhoneRuleHandler) ListPhoneRules(c *gin.Context) {
+	rules, err := h.phoneRuleService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch phone rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// CreatePhoneRule godoc
+// @Summary Create a phone rule
+// @Description Add an exact number or prefix to the allow, deny or test list
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body CreatePhoneRuleRequest true "Rule"
+// @Success 201 {object} model.PhoneRule
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/phone-rules [post]
+func (h *PhoneRuleHandler) CreatePhoneRule(c *gin.Context) {
+	var req CreatePhoneRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	rule, err := h.phoneRuleService.Create(strings.ToLower(req.List), strings.ToLower(req.MatchType), req.Value, req.Note)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPhoneRule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create phone rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// DeletePhoneRule godoc
+// @Summary Delete a phone rule
+// @Tags admin
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/phone-rules/{id} [delete]
+func (h *PhoneRuleHandler) DeletePhoneRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+
+	if err := h.phoneRuleService.Delete(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Phone rule not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete phone rule"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReauthenticateRequest struct {
+	OTP string `json:"otp" binding:"required"`
+}
+
+// RequestReauthentication godoc
+// @Summary Request a re-authentication OTP
+// @Description Send an OTP to the current user's phone number for stepping up the session
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/reauthenticate/request-otp [post]
+func (h *AuthHandler) RequestReauthentication(c *gin.Context) {
+	userID, _ := middleware.MustGetClaims(c).UserID()
+
+	if err := h.authService.StartReauthentication(userID); err != nil {
+		if writeOTPRequestError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate OTP"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "OTP sent successfully"})
+}
+
+// Reauthenticate godoc
+// @Summary Re-authenticate the session
+// @Description Verify a re-authentication OTP and return an access token with a fresh auth_time and the OTP assurance level. The refresh token is unchanged.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ReauthenticateRequest true "OTP"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/reauthenticate [post]
+func (h *AuthHandler) Reauthenticate(c *gin.Context) {
+	var req ReauthenticateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	claims := middleware.MustGetClaims(c)
+	userID, _ := claims.UserID()
+
+	tokens, err := h.authService.Reauthenticate(userID, claims.SessionID, req.OTP)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidOTP):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid OTP"})
+		case errors.Is(err, service.ErrSessionNotFound):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has ended"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to re-authenticate"})
+		}
+		return
+	}
+
+	h.cookies.respondAccess(c, tokens, middleware.CookieSession(c))
+}
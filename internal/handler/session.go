@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	sessionService service.SessionService
+}
+
+func NewSessionHandler(sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description List the devices the current user is logged in on; the session of the calling token is marked as current
+// @Tags sessions
+// @Produce json
+// @Success 200 {array} model.SessionResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /me/sessions [get]
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+	userID, _ := claims.UserID()
+
+	sessions, err := h.sessionService.ListSessions(userID, claims.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Sign the current user out of one session
+// @Tags sessions
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /me/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, _ := middleware.MustGetClaims(c).UserID()
+
+	if err := h.sessionService.RevokeSession(userID, c.Param("id")); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// RevokeOtherSessions godoc
+// @Summary Sign out everywhere else
+// @Description Revoke every session of the current user except the one making the request
+// @Tags sessions
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /me/sessions [delete]
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+	userID, _ := claims.UserID()
+
+	revoked, err := h.sessionService.RevokeOtherSessions(userID, claims.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Other sessions revoked", "revoked": revoked})
+}
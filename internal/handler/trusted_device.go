@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrustedDeviceHandler struct {
+	deviceService service.TrustedDeviceService
+}
+
+func NewTrustedDeviceHandler(deviceService service.TrustedDeviceService) *TrustedDeviceHandler {
+	return &TrustedDeviceHandler{deviceService: deviceService}
+}
+
+// ListDevices godoc
+// @Summary List trusted devices
+// @Description List the devices the current user can log in from without an OTP
+// @Tags devices
+// @Produce json
+// @Success 200 {array} model.TrustedDeviceResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /me/devices [get]
+func (h *TrustedDeviceHandler) ListDevices(c *gin.Context) {
+	userID, _ := middleware.MustGetClaims(c).UserID()
+
+	devices, err := h.deviceService.ListDevices(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list devices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, devices)
+}
+
+// RevokeDevice godoc
+// @Summary Forget a trusted device
+// @Description Require an OTP again for the next login from the device. Its current sessions stay active.
+// @Tags devices
+// @Produce json
+// @Param id path string true "Device ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /me/devices/{id} [delete]
+func (h *TrustedDeviceHandler) RevokeDevice(c *gin.Context) {
+	userID, _ := middleware.MustGetClaims(c).UserID()
+
+	if err := h.deviceService.RevokeDevice(userID, c.Param("id")); err != nil {
+		if errors.Is(err, service.ErrDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke device"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device revoked"})
+}
+
+// RevokeAllDevices godoc
+// @Summary Forget all trusted devices
+// @Description Require an OTP again for the next login from every device of the current user
+// @Tags devices
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /me/devices [delete]
+func (h *TrustedDeviceHandler) RevokeAllDevices(c *gin.Context) {
+	userID, _ := middleware.MustGetClaims(c).UserID()
+
+	if err := h.deviceService.RevokeAllDevices(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke devices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Devices revoked"})
+}
@@ -2,11 +2,15 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/model"
 	"otp-auth-service/internal/service"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type UserHandler struct {
@@ -19,13 +23,14 @@ func NewUserHandler(userService service.UserService) *UserHandler {
 
 // GetUser godoc
 // @Summary Get user by ID
-// @Description Retrieve a user by their ID
+// @Description Retrieve a user by their ID. Users other than the caller require the users:read scope and, for user tokens, the admin role.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
 // @Success 200 {object} model.UserResponse
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Security BearerAuth
@@ -38,6 +43,19 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
+	claims := middleware.MustGetClaims(c)
+	callerID, err := claims.UserID()
+	self := err == nil && uint(id) == callerID
+	if !self && !claims.HasScope(service.ScopeUsersRead) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient scope", "required_scope": service.ScopeUsersRead})
+		return
+	}
+	// Same rule as RequireRole for the listing
+	if !self && !claims.IsService() && !claims.HasRole(model.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient role"})
+		return
+	}
+
 	user, err := h.userService.GetUser(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
@@ -49,7 +67,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 
 // GetUsers godoc
 // @Summary Get users with pagination and search
-// @Description Retrieve a list of users with pagination and search capabilities
+// @Description Retrieve a list of users with pagination and search capabilities. Requires the users:read scope and, for user tokens, the admin role.
 // @Tags users
 // @Accept json
 // @Produce json
@@ -58,6 +76,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 // @Param search query string false "Search term"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Security BearerAuth
 // @Router /users [get]
@@ -102,13 +121,18 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 // @Security BearerAuth
 // @Router /me [get]
 func (h *UserHandler) GetMe(c *gin.Context) {
-	userID, exists := c.Get("user_id")
+	claims, exists := middleware.GetClaims(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
+	userID, err := claims.UserID()
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
 
-	user, err := h.userService.GetMe(userID.(uint))
+	user, err := h.userService.GetMe(userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -116,3 +140,64 @@ func (h *UserHandler) GetMe(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+type UpdateMeRequest struct {
+	DisplayName *string                `json:"display_name"`
+	Email       *string                `json:"email"`
+	Locale      *string                `json:"locale"`
+	Timezone    *string                `json:"timezone"`
+	AvatarURL   *string                `json:"avatar_url"`
+	Metadata    map[string]interface{} `json:"metadata"`
+}
+
+// UpdateMe godoc
+// @Summary Update current user's profile
+// @Description Change profile fields of the current user. Omitted fields are left alone and empty strings clear a field. The locale is a BCP 47 tag, the timezone an IANA zone, the avatar an https URL and metadata a JSON object of up to 4 KB that replaces the stored one.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body UpdateMeRequest true "Profile fields to change"
+// @Success 200 {object} model.UserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /me [patch]
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+	userID, err := claims.UserID()
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req UpdateMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, err := h.userService.UpdateProfile(userID, service.ProfileUpdate{
+		DisplayName: req.DisplayName,
+		Email:       req.Email,
+		Locale:      req.Locale,
+		Timezone:    req.Timezone,
+		AvatarURL:   req.AvatarURL,
+		Metadata:    req.Metadata,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidProfile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
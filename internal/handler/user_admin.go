@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"otp-auth-service/internal/service"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type UserAdminHandler struct {
+	userAdminService service.UserAdminService
+}
+
+func NewUserAdminHandler(userAdminService service.UserAdminService) *UserAdminHandler {
+	return &UserAdminHandler{userAdminService: userAdminService}
+}
+
+type AdminReasonRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type SetStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	// SuspendedUntil optionally ends a suspension.
+	SuspendedUntil *time.Time `json:"suspended_until"`
+	Reason         string     `json:"reason" binding:"required"`
+}
+
+type SetRolesRequest struct {
+	Roles  []string `json:"roles"`
+	Reason string   `json:"reason" binding:"required"`
+}
+
+// RevokeUserTokens godoc
+// @Summary Revoke all tokens of a user
+// @Description Invalidate every access and refresh token issued to the user; the reason is written to the audit log
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body AdminReasonRequest true "Reason"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/users/{id}/revoke-tokens [post]
+func (h *UserAdminHandler) RevokeUserTokens(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req AdminReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	err = h.userAdminService.RevokeAllTokens(uint(id), adminActor(c), strings.TrimSpace(req.Reason))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All tokens revoked"})
+}
+
+type SetMetadataRequest struct {
+	Metadata map[string]interface{} `json:"metadata"`
+	Reason   string                 `json:"reason" binding:"required"`
+}
+
+// SetUserMetadata godoc
+// @Summary Set the metadata of a user
+// @Description Replace the user's metadata, a JSON object such as {"tenant_id": "acme", "plan": "pro"}. Keys listed in CLAIMS_METADATA_KEYS are copied into the user's next access tokens. The change is written to the audit log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body SetMetadataRequest true "Metadata and reason"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/users/{id}/metadata [put]
+func (h *UserAdminHandler) SetUserMetadata(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req SetMetadataRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	err = h.userAdminService.SetMetadata(uint(id), req.Metadata, adminActor(c), strings.TrimSpace(req.Reason))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set metadata"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Metadata updated"})
+}
+
+// SetUserRoles godoc
+// @Summary Set the roles of a user
+// @Description Replace the user's roles. An empty list makes the user a regular user. Existing access tokens are invalidated; the change is written to the audit log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body SetRolesRequest true "Roles and reason"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/users/{id}/roles [put]
+func (h *UserAdminHandler) SetUserRoles(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req SetRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	err = h.userAdminService.SetRoles(uint(id), req.Roles, adminActor(c), strings.TrimSpace(req.Reason))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownRole):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set roles"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Roles updated"})
+}
+
+// RestoreUser godoc
+// @Summary Restore a deleted user
+// @Description Cancel the deletion of an account that is still in its grace period. The user logs in again with an OTP. The reason is written to the audit log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body AdminReasonRequest true "Reason"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/users/{id}/restore [post]
+func (h *UserAdminHandler) RestoreUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req AdminReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	err = h.userAdminService.Restore(uint(id), adminActor(c), strings.TrimSpace(req.Reason))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No deleted user with this ID"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User restored"})
+}
+
+// SetUserStatus godoc
+// @Summary Set the status of a user
+// @Description Make the user active, suspended or banned. Suspended and banned users cannot log in, and all their tokens, sessions and trusted devices are revoked. A suspension may carry suspended_until, after which the user can log in again. The change is written to the audit log, see GET /admin/audit-logs?target=user:{id}.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body SetStatusRequest true "Status and reason"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminToken
+// @Router /admin/users/{id}/status [put]
+func (h *UserAdminHandler) SetUserStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req SetStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	status := strings.ToLower(strings.TrimSpace(req.Status))
+	err = h.userAdminService.SetStatus(uint(id), status, req.SuspendedUntil, adminActor(c), strings.TrimSpace(req.Reason))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidStatus):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set status"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Status updated"})
+}
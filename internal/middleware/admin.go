@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminMiddleware struct {
+	apiToken string
+}
+
+func NewAdminMiddleware(apiToken string) *AdminMiddleware {
+	return &AdminMiddleware{apiToken: apiToken}
+}
+
+// RequireAdmin checks the X-Admin-Token header against the configured admin
+// token. When no token is configured every request is rejected.
+func (m *AdminMiddleware) RequireAdmin(c *gin.Context) {
+	if m.apiToken == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin API is disabled"})
+		c.Abort()
+		return
+	}
+
+	token := c.GetHeader("X-Admin-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(m.apiToken)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin token"})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
@@ -2,70 +2,150 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"otp-auth-service/internal/service"
+	"otp-auth-service/internal/token"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 )
 
+// ClaimsKey is the gin context key under which ValidateToken stores the
+// parsed *token.Claims.
+const ClaimsKey = "claims"
+
 type AuthMiddleware struct {
-	jwtSecret string
+	validator service.TokenValidator
+	dpop      service.DPoPService
+}
+
+func NewAuthMiddleware(validator service.TokenValidator, dpop service.DPoPService) *AuthMiddleware {
+	return &AuthMiddleware{validator: validator, dpop: dpop}
 }
 
-func NewAuthMiddleware() *AuthMiddleware {
-	return &AuthMiddleware{}
+// tokenPolicy lists which kinds of callers an endpoint accepts besides
+// users of the first-party login.
+type tokenPolicy struct {
+	// clients allows tokens issued to OAuth clients on behalf of a user.
+	clients bool
+	// services allows service clients, by client_credentials token or
+	// X-API-Key.
+	services bool
 }
 
+// ValidateToken accepts access tokens from the first-party login only,
+// as a bearer token, a DPoP-bound token with its proof or the access token
+// cookie of a browser session. Tokens issued to OAuth clients are rejected.
 func (m *AuthMiddleware) ValidateToken(c *gin.Context) {
+	m.validate(c, tokenPolicy{})
+}
+
+// ValidateClientToken also accepts access tokens issued to OAuth clients on
+// behalf of a user, for endpoints such as userinfo that serve relying
+// parties.
+func (m *AuthMiddleware) ValidateClientToken(c *gin.Context) {
+	m.validate(c, tokenPolicy{clients: true})
+}
+
+// ValidateServiceToken also accepts service clients, authenticated by a
+// client_credentials token or an X-API-Key header. Routes using it should
+// check scopes with RequireScope.
+func (m *AuthMiddleware) ValidateServiceToken(c *gin.Context) {
+	m.validate(c, tokenPolicy{services: true})
+}
+
+func (m *AuthMiddleware) validate(c *gin.Context, policy tokenPolicy) {
+	var claims *token.Claims
+	var err error
+	var tokenString string
+	fromCookie, dpopScheme := false, false
+
 	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
+	cookie, _ := c.Cookie(AccessTokenCookie)
+
+	switch apiKey := c.GetHeader("X-API-Key"); {
+	case apiKey != "" && policy.services:
+		claims, err = m.validator.ValidateAPIKey(apiKey)
+	case authHeader != "":
+		tokenString, dpopScheme = strings.CutPrefix(authHeader, "DPoP ")
+		if !dpopScheme {
+			var ok bool
+			tokenString, ok = strings.CutPrefix(authHeader, "Bearer ")
+			if !ok {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Bearer token required"})
+				c.Abort()
+				return
+			}
+		}
+
+		claims, err = m.validator.ValidateAccessToken(tokenString)
+	case cookie != "":
+		// Browsers attach the cookie to cross-site requests too
+		if !ValidCSRF(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "CSRF token missing or invalid"})
+			c.Abort()
+			return
+		}
+
+		fromCookie = true
+		tokenString = cookie
+		claims, err = m.validator.ValidateAccessToken(cookie)
+	default:
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
 		c.Abort()
 		return
 	}
-
-	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-	if tokenString == authHeader {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Bearer token required"})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		case errors.Is(err, service.ErrAccountDeleted):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account has been deleted", "code": "account_deleted"})
+		case errors.Is(err, service.ErrAccountSuspended):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended", "code": "account_suspended"})
+		case errors.Is(err, service.ErrAccountBanned):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is banned", "code": "account_banned"})
+		case errors.Is(err, service.ErrTokenRevoked):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+		}
 		c.Abort()
 		return
 	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(m.jwtSecret), nil
-	})
-
-	if err != nil || !token.Valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	switch {
+	case claims.IsService() && !policy.services:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Token was issued to a service client"})
 		c.Abort()
 		return
-	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+	case claims.ClientID != "" && !claims.IsService() && !policy.clients:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Token was issued to an OAuth client"})
 		c.Abort()
 		return
 	}
 
-	// Convert user_id from interface{} to uint
-	userIDFloat, ok := claims["user_id"].(float64)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user_id in token"})
-		c.Abort()
+	if tokenString != "" && !m.checkDPoP(c, claims, tokenString, dpopScheme) {
 		return
 	}
-	userID := uint(userIDFloat)
 
-	phone, ok := claims["phone"].(string)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid phone in token"})
-		c.Abort()
-		return
+	c.Set(ClaimsKey, claims)
+	c.Set(cookieSessionKey, fromCookie)
+	c.Next()
+}
+
+// GetClaims returns the claims of the token validated by ValidateToken.
+func GetClaims(c *gin.Context) (*token.Claims, bool) {
+	value, exists := c.Get(ClaimsKey)
+	if !exists {
+		return nil, false
 	}
+	claims, ok := value.(*token.Claims)
+	return claims, ok
+}
 
-	c.Set("user_id", userID)
-	c.Set("phone", phone)
-	c.Next()
+// MustGetClaims is GetClaims for routes behind ValidateToken.
+func MustGetClaims(c *gin.Context) *token.Claims {
+	return c.MustGet(ClaimsKey).(*token.Claims)
 }
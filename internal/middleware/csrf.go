@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cookies of browser sessions. The access and refresh tokens are HttpOnly;
+// the CSRF token is readable by scripts, which echo it in CSRFHeader.
+const (
+	AccessTokenCookie  = "access_token"
+	RefreshTokenCookie = "refresh_token"
+	CSRFCookie         = "csrf_token"
+	CSRFHeader         = "X-CSRF-Token"
+)
+
+// cookieSessionKey marks requests authenticated by AccessTokenCookie.
+const cookieSessionKey = "cookie_session"
+
+// ValidCSRF reports whether a request from a cookie session may proceed.
+// Safe methods always may; anything else must repeat the CSRF cookie in
+// CSRFHeader, which a cross-site form cannot do.
+func ValidCSRF(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	cookie, err := c.Cookie(CSRFCookie)
+	if err != nil || cookie == "" {
+		return false
+	}
+	header := c.GetHeader(CSRFHeader)
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
+
+// CookieSession reports whether the request was authenticated by the
+// access token cookie rather than an Authorization header.
+func CookieSession(c *gin.Context) bool {
+	return c.GetBool(cookieSessionKey)
+}
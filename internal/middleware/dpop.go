@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"otp-auth-service/internal/service"
+	"otp-auth-service/internal/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DPoP request and response headers (RFC 9449).
+const (
+	DPoPHeader      = "DPoP"
+	DPoPNonceHeader = "DPoP-Nonce"
+)
+
+// RequestURL rebuilds the URL the client sent the request to, for checking
+// the htu claim of DPoP proofs. Behind a TLS-terminating proxy the scheme
+// comes from X-Forwarded-Proto.
+func RequestURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + c.Request.URL.Path
+}
+
+// SetDPoPNonce sends a fresh nonce in the DPoP-Nonce header. When one
+// cannot be issued the header is left out and the client retries without.
+func SetDPoPNonce(c *gin.Context, dpop service.DPoPService) {
+	if nonce, err := dpop.Nonce(); err == nil {
+		c.Header(DPoPNonceHeader, nonce)
+	}
+}
+
+// checkDPoP verifies the proof sent with a DPoP-bound token, and rejects a
+// bound token sent as a plain bearer token. It writes the error response
+// and reports false when the request must not proceed.
+func (m *AuthMiddleware) checkDPoP(c *gin.Context, claims *token.Claims, accessToken string, dpopScheme bool) bool {
+	if claims.Confirmation == nil || claims.Confirmation.JKT == "" {
+		// The DPoP scheme promises a bound token
+		if dpopScheme {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return false
+		}
+		return true
+	}
+	if !dpopScheme && c.GetHeader("Authorization") != "" {
+		c.Header("WWW-Authenticate", `DPoP error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token is DPoP-bound"})
+		c.Abort()
+		return false
+	}
+
+	jkt, err := m.dpop.VerifyProof(c.GetHeader(DPoPHeader), c.Request.Method, RequestURL(c), accessToken)
+	switch {
+	case errors.Is(err, service.ErrUseDPoPNonce):
+		SetDPoPNonce(c, m.dpop)
+		c.Header("WWW-Authenticate", `DPoP error="use_dpop_nonce"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "DPoP nonce required", "code": "use_dpop_nonce"})
+	case errors.Is(err, service.ErrInvalidDPoPProof) || (err == nil && jkt != claims.Confirmation.JKT):
+		c.Header("WWW-Authenticate", `DPoP error="invalid_dpop_proof"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid DPoP proof"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+	default:
+		return true
+	}
+	c.Abort()
+	return false
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole lets the request through when the validated token carries any
+// of roles. Service client tokens carry no roles and are passed on, so pair
+// RequireRole with RequireScope on routes that accept them. It must run
+// after ValidateToken or ValidateServiceToken.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			c.Abort()
+			return
+		}
+		if claims.IsService() {
+			c.Next()
+			return
+		}
+
+		for _, role := range roles {
+			if claims.HasRole(role) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient role"})
+		c.Abort()
+	}
+}
+
+// RequireScope lets the request through when the validated token carries
+// every one of scopes. It must run after ValidateToken or
+// ValidateClientToken.
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		for _, scope := range scopes {
+			if !claims.HasScope(scope) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient scope", "required_scope": scope})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"otp-auth-service/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAuthentication rejects tokens whose user last authenticated more
+// than maxAge ago or at an assurance level below minACR. Clients answer the
+// insufficient_user_authentication error (RFC 9470) by re-authenticating
+// at /auth/reauthenticate. A zero maxAge or empty minACR skips that check.
+func RequireAuthentication(maxAge time.Duration, minACR string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := MustGetClaims(c)
+
+		fresh := claims.AuthTime != nil && (maxAge <= 0 || time.Since(claims.AuthTime.Time) <= maxAge)
+		if fresh && service.ACRSatisfies(claims.ACR, minACR) {
+			c.Next()
+			return
+		}
+
+		challenge := `Bearer error="insufficient_user_authentication"`
+		response := gin.H{
+			"error": "Re-authentication required",
+			"code":  "insufficient_user_authentication",
+		}
+		if maxAge > 0 {
+			seconds := int(maxAge.Seconds())
+			challenge += `, max_age=` + strconv.Itoa(seconds)
+			response["max_age"] = seconds
+		}
+		if minACR != "" {
+			challenge += fmt.Sprintf(`, acr_values=%q`, minACR)
+			response["acr_values"] = minACR
+		}
+
+		c.Header("WWW-Authenticate", challenge)
+		c.JSON(http.StatusUnauthorized, response)
+		c.Abort()
+	}
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// AccountExport is everything stored about a user, as handed to them by
+// GET /me/export.
+type AccountExport struct {
+	ExportedAt time.Time    `json:"exported_at"`
+	Profile    UserResponse `json:"profile"`
+	// Attributes is the admin-managed metadata of the user.
+	Attributes         map[string]interface{} `json:"attributes"`
+	PhoneNumberHistory []PhoneNumberHistory   `json:"phone_number_history"`
+	Sessions           []SessionExport        `json:"sessions"`
+	TrustedDevices     []TrustedDeviceExport  `json:"trusted_devices"`
+	OTPRequests        []OTPRequestResponse   `json:"otp_requests"`
+}
+
+type SessionExport struct {
+	ID         string     `json:"id"`
+	DeviceName string     `json:"device_name"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	AuthTime   time.Time  `json:"auth_time"`
+	AMR        []string   `json:"amr"`
+	ACR        string     `json:"acr"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+type TrustedDeviceExport struct {
+	TrustedDeviceResponse
+	RevokedAt *time.Time `json:"revoked_at"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// AuditLog records an administrative action taken against a target such as
+// a phone number or user.
+type AuditLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Actor     string    `json:"actor" gorm:"column:actor"`
+	Action    string    `json:"action" gorm:"column:action"`
+	Target    string    `json:"target" gorm:"column:target"`
+	Reason    string    `json:"reason" gorm:"column:reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (*AuditLog) TableName() string {
+	return "audit_logs"
+}
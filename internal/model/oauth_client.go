@@ -0,0 +1,105 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Grant types a client can be registered for.
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+)
+
+// OAuthClient is a registered relying party or service client. Public
+// clients have no secret and must rely on PKCE alone. Service clients use
+// the client_credentials grant or send their secret as an API key.
+type OAuthClient struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	ClientID     string     `json:"client_id" gorm:"column:client_id;uniqueIndex"`
+	SecretHash   string     `json:"-" gorm:"column:secret_hash;index"`
+	Name         string     `json:"name" gorm:"column:name"`
+	RedirectURIs string     `json:"-" gorm:"column:redirect_uris"`
+	Scopes       string     `json:"-" gorm:"column:scopes"`
+	GrantTypes   string     `json:"-" gorm:"column:grant_types;not null;default:'authorization_code'"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty" gorm:"column:expires_at"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty" gorm:"column:last_used_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty" gorm:"column:revoked_at"`
+}
+
+func (*OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+func (c *OAuthClient) Public() bool {
+	return c.SecretHash == ""
+}
+
+// RedirectURIList returns the registered redirect URIs, stored space
+// separated.
+func (c *OAuthClient) RedirectURIList() []string {
+	return strings.Fields(c.RedirectURIs)
+}
+
+// GrantTypeList returns the grant types the client may use, stored space
+// separated.
+func (c *OAuthClient) GrantTypeList() []string {
+	return strings.Fields(c.GrantTypes)
+}
+
+func (c *OAuthClient) AllowsGrant(grantType string) bool {
+	return slices.Contains(c.GrantTypeList(), grantType)
+}
+
+// ScopeList returns the scopes the client may request, stored space
+// separated.
+func (c *OAuthClient) ScopeList() []string {
+	return strings.Fields(c.Scopes)
+}
+
+type OAuthClientResponse struct {
+	ClientID     string     `json:"client_id"`
+	Name         string     `json:"name"`
+	RedirectURIs []string   `json:"redirect_uris"`
+	Scopes       []string   `json:"scopes"`
+	GrantTypes   []string   `json:"grant_types"`
+	Public       bool       `json:"public"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	// ClientSecret is only returned when the client is created or its
+	// secret is rotated.
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// AuthorizationRequest is a validated /oauth/authorize request waiting for
+// the user to log in.
+type AuthorizationRequest struct {
+	ID                  string    `json:"id"`
+	ClientID            string    `json:"client_id"`
+	RedirectURI         string    `json:"redirect_uri"`
+	Scope               string    `json:"scope"`
+	State               string    `json:"state"`
+	Nonce               string    `json:"nonce"`
+	CodeChallenge       string    `json:"code_challenge"`
+	CodeChallengeMethod string    `json:"code_challenge_method"`
+	ExpiresAt           time.Time `json:"expires_at"`
+}
+
+// AuthorizationCode is what an issued code redeems for at the token
+// endpoint.
+type AuthorizationCode struct {
+	ClientID            string    `json:"client_id"`
+	UserID              uint      `json:"user_id"`
+	SessionID           string    `json:"session_id"`
+	RedirectURI         string    `json:"redirect_uri"`
+	Scope               string    `json:"scope"`
+	Nonce               string    `json:"nonce"`
+	CodeChallenge       string    `json:"code_challenge"`
+	CodeChallengeMethod string    `json:"code_challenge_method"`
+	AuthTime            time.Time `json:"auth_time"`
+}
@@ -2,9 +2,20 @@ package model
 
 import "time"
 
+const (
+	OTPPurposeLogin       = "login"
+	OTPPurposePhoneChange = "phone_change"
+	OTPPurposeReauth      = "reauth"
+	OTPPurposeDeletion    = "account_deletion"
+)
+
+// OTPPurposes lists every purpose an OTP can be issued for.
+var OTPPurposes = []string{OTPPurposeLogin, OTPPurposePhoneChange, OTPPurposeReauth, OTPPurposeDeletion}
+
 type OTPRequest struct {
 	ID          uint      `gorm:"primaryKey"`
 	PhoneNumber string    `gorm:"column:phone_number"`
+	Country     string    `gorm:"column:country"`
 	RequestedAt time.Time `gorm:"column:requested_at"`
 	Successful  bool      `gorm:"column:successful"`
 }
@@ -16,6 +27,7 @@ func (*OTPRequest) TableName() string {
 type OTPRequestResponse struct {
 	ID          uint      `json:"id"`
 	PhoneNumber string    `json:"phone_number"`
+	Country     string    `json:"country"`
 	RequestedAt time.Time `json:"requested_at"`
 	Successful  bool      `json:"successful"`
 }
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// PhoneNumberHistory records a user's previous phone number each time it is
+// changed.
+type PhoneNumberHistory struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	UserID         uint      `json:"user_id" gorm:"column:user_id"`
+	OldPhoneNumber string    `json:"old_phone_number" gorm:"column:old_phone_number"`
+	NewPhoneNumber string    `json:"new_phone_number" gorm:"column:new_phone_number"`
+	ChangedAt      time.Time `json:"changed_at" gorm:"column:changed_at"`
+}
+
+func (*PhoneNumberHistory) TableName() string {
+	return "phone_number_history"
+}
+
+// PhoneOwnership is a period during which a user held a phone number. From
+// is zero when nobody held the number before, and Until is zero while the
+// user still holds it.
+type PhoneOwnership struct {
+	PhoneNumber string
+	From        time.Time
+	Until       time.Time
+}
+
+// Covers reports whether something happened to phoneNumber at t while the
+// user held it.
+func (o PhoneOwnership) Covers(phoneNumber string, t time.Time) bool {
+	if phoneNumber != o.PhoneNumber || t.Before(o.From) {
+		return false
+	}
+	return o.Until.IsZero() || t.Before(o.Until)
+}
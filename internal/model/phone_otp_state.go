@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// PhoneOTPState is a snapshot of the rate limiting and lockout state of a
+// phone number, as seen by support tooling.
+type PhoneOTPState struct {
+	PhoneNumber        string     `json:"phone_number"`
+	Country            string     `json:"country"`
+	List               string     `json:"list,omitempty"`
+	RateLimit          int        `json:"rate_limit"`
+	WindowSeconds      int        `json:"window_seconds"`
+	RequestsInWindow   int        `json:"requests_in_window"`
+	SuccessfulInWindow int        `json:"successful_in_window"`
+	RemainingQuota     int        `json:"remaining_quota"`
+	LockedOut          bool       `json:"locked_out"`
+	LockedUntil        *time.Time `json:"locked_until,omitempty"`
+	ChallengeRequired  bool       `json:"challenge_required"`
+	OTPActive          bool       `json:"otp_active"`
+	OTPTTLSeconds      int        `json:"otp_ttl_seconds"`
+	CountersResetAt    *time.Time `json:"counters_reset_at,omitempty"`
+	// MaxAttempts wrong guesses void an OTP
+	MaxAttempts int               `json:"max_attempts"`
+	OTPs        []PurposeOTPState `json:"otps"`
+}
+
+// PurposeOTPState describes the active OTP of one purpose and the wrong
+// guesses made at it.
+type PurposeOTPState struct {
+	Purpose        string `json:"purpose"`
+	Active         bool   `json:"active"`
+	TTLSeconds     int    `json:"ttl_seconds"`
+	FailedAttempts int    `json:"failed_attempts"`
+	AttemptsLeft   int    `json:"attempts_left"`
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	PhoneListAllow = "allow"
+	PhoneListDeny  = "deny"
+	PhoneListTest  = "test"
+
+	PhoneMatchExact  = "exact"
+	PhoneMatchPrefix = "prefix"
+)
+
+// PhoneRule is an admin-managed entry on one of the phone lists. Deny rules
+// block OTP requests, allow rules bypass rate limiting and challenges, and
+// test rules accept the configured fixed OTP without sending anything.
+type PhoneRule struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	List      string    `json:"list" gorm:"column:list"`
+	MatchType string    `json:"match_type" gorm:"column:match_type"`
+	Value     string    `json:"value" gorm:"column:value"`
+	Note      string    `json:"note" gorm:"column:note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (*PhoneRule) TableName() string {
+	return "phone_rules"
+}
+
+// Matches reports whether phoneNumber is covered by the rule.
+func (r *PhoneRule) Matches(phoneNumber string) bool {
+	switch r.MatchType {
+	case PhoneMatchExact:
+		return phoneNumber == r.Value
+	case PhoneMatchPrefix:
+		return strings.HasPrefix(phoneNumber, r.Value)
+	}
+	return false
+}
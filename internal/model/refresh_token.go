@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// RefreshToken is an opaque refresh token stored as a SHA-256 hash. Tokens
+// rotated from one another share a FamilyID so the whole chain can be
+// revoked when a used token is presented again.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"column:user_id"`
+	FamilyID  string     `gorm:"column:family_id"`
+	TokenHash string     `gorm:"column:token_hash;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"column:expires_at"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+}
+
+func (*RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	// DeviceToken is set when the login registered a trusted device.
+	DeviceToken string `json:"device_token,omitempty"`
+}
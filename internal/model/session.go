@@ -0,0 +1,56 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Session is one login of a user on a device. Its ID is the family ID of the
+// refresh tokens issued for the login and the sid claim of its access tokens.
+type Session struct {
+	ID         string `gorm:"primaryKey;size:64"`
+	UserID     uint   `gorm:"column:user_id;index"`
+	DeviceName string `gorm:"column:device_name"`
+	IPAddress  string `gorm:"column:ip_address"`
+	UserAgent  string `gorm:"column:user_agent"`
+	// DPoPJKT is the thumbprint of the DPoP key the session's tokens are
+	// bound to, empty for bearer sessions.
+	DPoPJKT string `gorm:"column:dpop_jkt"`
+	// AuthTime, AMR and ACR describe the last time the user proved who
+	// they are in this session, at login or a later re-authentication. AMR
+	// is a space separated list of RFC 8176 method names.
+	AuthTime   time.Time  `gorm:"column:auth_time"`
+	AMR        string     `gorm:"column:amr"`
+	ACR        string     `gorm:"column:acr"`
+	CreatedAt  time.Time  `gorm:"column:created_at"`
+	LastSeenAt time.Time  `gorm:"column:last_seen_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+}
+
+func (*Session) TableName() string {
+	return "sessions"
+}
+
+func (s *Session) AMRList() []string {
+	return strings.Fields(s.AMR)
+}
+
+// SessionInfo describes the client a session is created or refreshed from.
+type SessionInfo struct {
+	DeviceName string
+	IPAddress  string
+	UserAgent  string
+	// DPoPJKT is the thumbprint of the key of a verified DPoP proof sent by
+	// the client.
+	DPoPJKT string
+}
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
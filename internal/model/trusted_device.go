@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// TrustedDevice is a device a user chose to remember after an OTP login.
+// Presenting its device token logs the user in without an OTP until
+// ExpiresAt. Only the hash of the token is stored.
+type TrustedDevice struct {
+	ID         string     `gorm:"primaryKey;size:64"`
+	UserID     uint       `gorm:"column:user_id;index"`
+	Name       string     `gorm:"column:name"`
+	TokenHash  string     `gorm:"column:token_hash;uniqueIndex"`
+	UserAgent  string     `gorm:"column:user_agent"`
+	CreatedAt  time.Time  `gorm:"column:created_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+	ExpiresAt  time.Time  `gorm:"column:expires_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+}
+
+func (*TrustedDevice) TableName() string {
+	return "trusted_devices"
+}
+
+type TrustedDeviceResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
@@ -1,17 +1,133 @@
 package model
 
 import (
+	"encoding/json"
+	"slices"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// Roles a user can be granted. Every user is implicitly a regular user.
+const (
+	RoleAdmin = "admin"
+)
+
+// Account statuses. Suspended and banned users cannot log in and their
+// existing tokens stop working; a suspension may end on its own.
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusBanned    = "banned"
 )
 
 type User struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	PhoneNumber string    `json:"phone_number" gorm:"uniqueIndex"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	PhoneNumber string `json:"phone_number" gorm:"uniqueIndex"`
+	Roles       string `json:"-" gorm:"column:roles;not null;default:''"`
+	// Metadata is a JSON object of attributes managed by admins, such as
+	// the tenant or plan, some of which may be copied into tokens.
+	Metadata string `json:"-" gorm:"column:metadata;type:jsonb;not null;default:'{}'"`
+
+	// Profile, edited by the user through PATCH /me
+	DisplayName string `json:"display_name" gorm:"column:display_name;not null;default:''"`
+	Email       string `json:"email" gorm:"column:email;not null;default:''"`
+	Locale      string `json:"locale" gorm:"column:locale;not null;default:''"`
+	Timezone    string `json:"timezone" gorm:"column:timezone;not null;default:''"`
+	AvatarURL   string `json:"avatar_url" gorm:"column:avatar_url;not null;default:''"`
+	// ProfileMetadata is a JSON object owned by the user. Unlike Metadata it
+	// never reaches tokens.
+	ProfileMetadata string `json:"-" gorm:"column:profile_metadata;type:jsonb;not null;default:'{}'"`
+
+	// Status is set by support. StatusReason and StatusChangedAt describe
+	// the last change; SuspendedUntil, when set, ends a suspension.
+	Status          string     `json:"status" gorm:"column:status;not null;default:'active'"`
+	StatusReason    string     `json:"-" gorm:"column:status_reason;not null;default:''"`
+	StatusChangedAt *time.Time `json:"-" gorm:"column:status_changed_at"`
+	SuspendedUntil  *time.Time `json:"-" gorm:"column:suspended_until"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt is set when the user deletes their account. Deleted users
+	// are hidden from queries until they are purged.
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// RoleList returns the user's roles, stored space separated.
+func (u *User) RoleList() []string {
+	return strings.Fields(u.Roles)
+}
+
+func (u *User) HasRole(role string) bool {
+	return slices.Contains(u.RoleList(), role)
+}
+
+// EffectiveStatus is the user's status at now, taking the end of a
+// suspension into account. Users created before statuses existed are
+// active.
+func (u *User) EffectiveStatus(now time.Time) string {
+	switch {
+	case u.Status == "":
+		return UserStatusActive
+	case u.Status == UserStatusSuspended && u.SuspendedUntil != nil && !now.Before(*u.SuspendedUntil):
+		return UserStatusActive
+	}
+	return u.Status
+}
+
+// MetadataMap decodes the user's metadata. Users created before metadata
+// existed have none.
+func (u *User) MetadataMap() (map[string]interface{}, error) {
+	metadata := map[string]interface{}{}
+	if u.Metadata == "" {
+		return metadata, nil
+	}
+	if err := json.Unmarshal([]byte(u.Metadata), &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
 }
 
 type UserResponse struct {
-	ID          uint      `json:"id"`
-	PhoneNumber string    `json:"phone_number"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          uint            `json:"id"`
+	PhoneNumber string          `json:"phone_number"`
+	Roles       []string        `json:"roles"`
+	DisplayName string          `json:"display_name"`
+	Email       string          `json:"email"`
+	Locale      string          `json:"locale"`
+	Timezone    string          `json:"timezone"`
+	AvatarURL   string          `json:"avatar_url"`
+	Metadata    json.RawMessage `json:"metadata" swaggertype:"object"`
+	Status      string          `json:"status"`
+	// SuspendedUntil is set while a suspension with an end is in force.
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Response describes the user to API clients.
+func (u *User) Response() UserResponse {
+	metadata := json.RawMessage(u.ProfileMetadata)
+	if len(metadata) == 0 {
+		metadata = json.RawMessage("{}")
+	}
+	response := UserResponse{
+		ID:          u.ID,
+		PhoneNumber: u.PhoneNumber,
+		Roles:       u.RoleList(),
+		DisplayName: u.DisplayName,
+		Email:       u.Email,
+		Locale:      u.Locale,
+		Timezone:    u.Timezone,
+		AvatarURL:   u.AvatarURL,
+		Metadata:    metadata,
+		Status:      u.EffectiveStatus(time.Now()),
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+	}
+	if response.Status == UserStatusSuspended {
+		response.SuspendedUntil = u.SuspendedUntil
+	}
+	return response
 }
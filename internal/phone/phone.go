@@ -0,0 +1,110 @@
+package phone
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+var (
+	ErrInvalidNumber = errors.New("invalid phone number")
+	ErrNotMobile     = errors.New("phone number is not a mobile number")
+)
+
+// Number is a parsed, validated phone number.
+type Number struct {
+	// E164 is the canonical form used as the key everywhere, e.g. +15551234567.
+	E164 string
+	// Region is the ISO 3166-1 alpha-2 code of the number's country.
+	Region      string
+	CountryCode int
+}
+
+type Parser struct {
+	defaultRegion string
+}
+
+// NewParser returns a parser that reads numbers without an international
+// prefix as national numbers of defaultRegion.
+func NewParser(defaultRegion string) *Parser {
+	return &Parser{defaultRegion: strings.ToUpper(defaultRegion)}
+}
+
+// Parse accepts the common ways users type a number ("+1 555...",
+// "001555...", "1555...") and returns its canonical E.164 form. Numbers that
+// cannot receive SMS are rejected with ErrNotMobile.
+func (p *Parser) Parse(raw string) (*Number, error) {
+	cleaned := clean(raw)
+	if cleaned == "" || cleaned == "+" {
+		return nil, ErrInvalidNumber
+	}
+
+	num, err := p.parse(cleaned)
+	if err != nil {
+		return nil, err
+	}
+
+	switch phonenumbers.GetNumberType(num) {
+	case phonenumbers.MOBILE, phonenumbers.FIXED_LINE_OR_MOBILE:
+	default:
+		return nil, ErrNotMobile
+	}
+
+	return &Number{
+		E164:        phonenumbers.Format(num, phonenumbers.E164),
+		Region:      phonenumbers.GetRegionCodeForNumber(num),
+		CountryCode: int(num.GetCountryCode()),
+	}, nil
+}
+
+func (p *Parser) parse(cleaned string) (*phonenumbers.PhoneNumber, error) {
+	num, err := phonenumbers.Parse(cleaned, p.defaultRegion)
+	if err == nil && phonenumbers.IsValidNumber(num) {
+		return num, nil
+	}
+
+	// Fall back to reading the digits as country code + number, which is
+	// how numbers without a leading + were stored historically.
+	if !strings.HasPrefix(cleaned, "+") {
+		num, err = phonenumbers.Parse("+"+cleaned, "")
+		if err == nil && phonenumbers.IsValidNumber(num) {
+			return num, nil
+		}
+	}
+
+	return nil, ErrInvalidNumber
+}
+
+// NormalizePrefix cleans a partial number such as a country or carrier
+// prefix used by phone rules, returning it in "+<digits>" form.
+func NormalizePrefix(raw string) string {
+	cleaned := clean(raw)
+	if !strings.HasPrefix(cleaned, "+") {
+		cleaned = "+" + cleaned
+	}
+	return cleaned
+}
+
+// clean strips formatting characters and turns a leading 00 international
+// prefix into +.
+func clean(raw string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(raw) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return ""
+		}
+	}
+
+	cleaned := b.String()
+	if strings.HasPrefix(cleaned, "00") {
+		cleaned = "+" + cleaned[2:]
+	}
+	return cleaned
+}
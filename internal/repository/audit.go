@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"otp-auth-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	Record(entry *model.AuditLog) error
+	FindByTarget(target string, limit int) ([]model.AuditLog, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Record(entry *model.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+// FindByTarget returns the most recent entries first. An empty target
+// returns entries for every target.
+func (r *auditRepository) FindByTarget(target string, limit int) ([]model.AuditLog, error) {
+	var entries []model.AuditLog
+
+	query := r.db.Model(&model.AuditLog{})
+	if target != "" {
+		query = query.Where("target = ?", target)
+	}
+
+	err := query.Order("created_at DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
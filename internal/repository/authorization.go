@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"otp-auth-service/internal/model"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AuthorizationRepository keeps pending authorization requests and issued
+// authorization codes in Redis until they expire or are redeemed.
+type AuthorizationRepository interface {
+	StoreRequest(request *model.AuthorizationRequest, expiration time.Duration) error
+	GetRequest(id string) (*model.AuthorizationRequest, error)
+	DeleteRequest(id string) error
+	StoreCode(codeHash string, code *model.AuthorizationCode, expiration time.Duration) error
+	ConsumeCode(codeHash string) (*model.AuthorizationCode, error)
+}
+
+type authorizationRepository struct {
+	client *redis.Client
+}
+
+func NewAuthorizationRepository(client *redis.Client) AuthorizationRepository {
+	return &authorizationRepository{client: client}
+}
+
+func (r *authorizationRepository) StoreRequest(request *model.AuthorizationRequest, expiration time.Duration) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	return r.client.Set(ctx, "oauth:request:"+request.ID, data, expiration).Err()
+}
+
+func (r *authorizationRepository) GetRequest(id string) (*model.AuthorizationRequest, error) {
+	ctx := context.Background()
+	data, err := r.client.Get(ctx, "oauth:request:"+id).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var request model.AuthorizationRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *authorizationRepository) DeleteRequest(id string) error {
+	ctx := context.Background()
+	return r.client.Del(ctx, "oauth:request:"+id).Err()
+}
+
+func (r *authorizationRepository) StoreCode(codeHash string, code *model.AuthorizationCode, expiration time.Duration) error {
+	data, err := json.Marshal(code)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	return r.client.Set(ctx, "oauth:code:"+codeHash, data, expiration).Err()
+}
+
+// ConsumeCode fetches and deletes the code in one step so it can be
+// redeemed only once.
+func (r *authorizationRepository) ConsumeCode(codeHash string) (*model.AuthorizationCode, error) {
+	ctx := context.Background()
+	data, err := r.client.GetDel(ctx, "oauth:code:"+codeHash).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var code model.AuthorizationCode
+	if err := json.Unmarshal(data, &code); err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type ChallengeRepository interface {
+	StoreChallenge(token string, expiration time.Duration) error
+	ConsumeChallenge(token string) (bool, error)
+}
+
+type challengeRepository struct {
+	client *redis.Client
+}
+
+func NewChallengeRepository(client *redis.Client) ChallengeRepository {
+	return &challengeRepository{client: client}
+}
+
+func (r *challengeRepository) StoreChallenge(token string, expiration time.Duration) error {
+	ctx := context.Background()
+	return r.client.Set(ctx, "challenge:"+token, 1, expiration).Err()
+}
+
+// ConsumeChallenge deletes the token and reports whether it still existed,
+// so every issued challenge can be redeemed at most once.
+func (r *challengeRepository) ConsumeChallenge(token string) (bool, error) {
+	ctx := context.Background()
+	deleted, err := r.client.Del(ctx, "challenge:"+token).Result()
+	if err != nil {
+		return false, err
+	}
+	return deleted == 1, nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type DPoPRepository interface {
+	// MarkProofUsed records a proof's jti and reports false when it was
+	// seen before.
+	MarkProofUsed(jti string, expiration time.Duration) (bool, error)
+	StoreNonce(nonce string, expiration time.Duration) error
+	NonceExists(nonce string) (bool, error)
+}
+
+type dpopRepository struct {
+	client *redis.Client
+}
+
+func NewDPoPRepository(client *redis.Client) DPoPRepository {
+	return &dpopRepository{client: client}
+}
+
+func (r *dpopRepository) MarkProofUsed(jti string, expiration time.Duration) (bool, error) {
+	ctx := context.Background()
+	return r.client.SetNX(ctx, "dpop:jti:"+jti, 1, expiration).Result()
+}
+
+func (r *dpopRepository) StoreNonce(nonce string, expiration time.Duration) error {
+	ctx := context.Background()
+	return r.client.Set(ctx, "dpop:nonce:"+nonce, 1, expiration).Err()
+}
+
+func (r *dpopRepository) NonceExists(nonce string) (bool, error) {
+	ctx := context.Background()
+	count, err := r.client.Exists(ctx, "dpop:nonce:"+nonce).Result()
+	return count > 0, err
+}
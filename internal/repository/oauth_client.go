@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"otp-auth-service/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lastUsedResolution limits how often last_used_at is written for a busy
+// client.
+const lastUsedResolution = time.Minute
+
+type OAuthClientRepository interface {
+	Create(client *model.OAuthClient) error
+	FindByClientID(clientID string) (*model.OAuthClient, error)
+	FindBySecretHash(secretHash string) (*model.OAuthClient, error)
+	List() ([]model.OAuthClient, error)
+	UpdateSecretHash(clientID, secretHash string) (bool, error)
+	TouchLastUsed(id uint) error
+	Revoke(clientID string) (bool, error)
+}
+
+type oauthClientRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthClientRepository(db *gorm.DB) OAuthClientRepository {
+	return &oauthClientRepository{db: db}
+}
+
+func (r *oauthClientRepository) Create(client *model.OAuthClient) error {
+	return r.db.Create(client).Error
+}
+
+// FindByClientID returns the client unless it was revoked or has expired.
+func (r *oauthClientRepository) FindByClientID(clientID string) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	err := r.active().Where("client_id = ?", clientID).First(&client).Error
+	return &client, err
+}
+
+// FindBySecretHash looks up an active confidential client by its secret,
+// for API key authentication.
+func (r *oauthClientRepository) FindBySecretHash(secretHash string) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	err := r.active().Where("secret_hash = ? AND secret_hash <> ''", secretHash).First(&client).Error
+	return &client, err
+}
+
+func (r *oauthClientRepository) List() ([]model.OAuthClient, error) {
+	var clients []model.OAuthClient
+	err := r.db.Order("created_at DESC").Find(&clients).Error
+	return clients, err
+}
+
+// UpdateSecretHash reports false when no active confidential client has
+// clientID.
+func (r *oauthClientRepository) UpdateSecretHash(clientID, secretHash string) (bool, error) {
+	result := r.active().Model(&model.OAuthClient{}).
+		Where("client_id = ? AND secret_hash <> ''", clientID).
+		Update("secret_hash", secretHash)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *oauthClientRepository) TouchLastUsed(id uint) error {
+	now := time.Now().UTC()
+	return r.db.Model(&model.OAuthClient{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-lastUsedResolution)).
+		Update("last_used_at", now).Error
+}
+
+// Revoke reports false when no active client has clientID.
+func (r *oauthClientRepository) Revoke(clientID string) (bool, error) {
+	result := r.db.Model(&model.OAuthClient{}).
+		Where("client_id = ? AND revoked_at IS NULL", clientID).
+		Update("revoked_at", time.Now().UTC())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *oauthClientRepository) active() *gorm.DB {
+	return r.db.Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", time.Now().UTC())
+}
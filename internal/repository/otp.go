@@ -10,12 +10,22 @@ import (
 )
 
 type OTPRepository interface {
-	StoreOTP(phoneNumber, otp string, expiration time.Duration) error
-	GetOTP(phoneNumber string) (string, error)
+	StoreOTP(purpose, phoneNumber, otp string, expiration time.Duration) error
+	GetOTP(purpose, phoneNumber string) (string, error)
+	GetOTPTTL(purpose, phoneNumber string) (time.Duration, error)
+	DeleteOTP(purpose, phoneNumber string) error
+	ConsumeOTP(purpose, phoneNumber, otp string, maxAttempts int) (bool, error)
+	GetOTPAttempts(purpose, phoneNumber string) (int, error)
+	ResetOTPAttempts(purpose, phoneNumber string) error
 	IncrementRequestCount(phoneNumber string, expiration time.Duration) (int, error)
-	RecordOTPRequest(phoneNumber string, successful bool) error
+	IncrementIPRequestCount(ip string, expiration time.Duration) (int, error)
+	RecordOTPRequest(phoneNumber, country string, successful bool) error
 	GetRequestCount(phoneNumber string, since time.Time) (int, error)
 	GetSuccessfulRequestCount(phoneNumber string, since time.Time) (int, error)
+	GetRequestTimes(phoneNumber string, since time.Time) ([]time.Time, error)
+	ListRequests(ownerships []model.PhoneOwnership) ([]model.OTPRequest, error)
+	ResetRequestCount(phoneNumber string, expiration time.Duration) error
+	GetRequestCountResetAt(phoneNumber string) (time.Time, error)
 }
 
 type otpRepository struct {
@@ -27,25 +37,150 @@ func NewOTPRepository(client *redis.Client, db *gorm.DB) OTPRepository {
 	return &otpRepository{client: client, db: db}
 }
 
-func (r *otpRepository) StoreOTP(phoneNumber, otp string, expiration time.Duration) error {
+// otpKey scopes OTPs by purpose so a login code cannot be used to confirm a
+// phone change and vice versa. Login codes keep their original key.
+func otpKey(purpose, phoneNumber string) string {
+	if purpose == model.OTPPurposeLogin {
+		return "otp:" + phoneNumber
+	}
+	return "otp:" + purpose + ":" + phoneNumber
+}
+
+// otpAttemptsKey counts wrong guesses at the current OTP for purpose.
+func otpAttemptsKey(purpose, phoneNumber string) string {
+	return "otp:attempts:" + purpose + ":" + phoneNumber
+}
+
+// StoreOTP replaces the active OTP and gives the new one a fresh attempt
+// budget.
+func (r *otpRepository) StoreOTP(purpose, phoneNumber, otp string, expiration time.Duration) error {
 	ctx := context.Background()
-	return r.client.Set(ctx, "otp:"+phoneNumber, otp, expiration).Err()
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, otpKey(purpose, phoneNumber), otp, expiration)
+		pipe.Del(ctx, otpAttemptsKey(purpose, phoneNumber))
+		return nil
+	})
+	return err
+}
+
+func (r *otpRepository) GetOTP(purpose, phoneNumber string) (string, error) {
+	ctx := context.Background()
+	return r.client.Get(ctx, otpKey(purpose, phoneNumber)).Result()
+}
+
+// GetOTPTTL returns the remaining lifetime of the active OTP, or zero when
+// there is none.
+func (r *otpRepository) GetOTPTTL(purpose, phoneNumber string) (time.Duration, error) {
+	ctx := context.Background()
+	ttl, err := r.client.TTL(ctx, otpKey(purpose, phoneNumber)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (r *otpRepository) DeleteOTP(purpose, phoneNumber string) error {
+	ctx := context.Background()
+	return r.client.Del(ctx, otpKey(purpose, phoneNumber), otpAttemptsKey(purpose, phoneNumber)).Err()
+}
+
+// consumeOTPScript deletes the OTP in KEYS[1] when it equals ARGV[1]. Any
+// other guess is counted in KEYS[2], which lives as long as the OTP, and
+// the ARGV[2]th wrong guess deletes the OTP.
+var consumeOTPScript = redis.NewScript(`
+local stored = redis.call("GET", KEYS[1])
+if not stored then
+	return 0
+end
+if stored == ARGV[1] then
+	redis.call("DEL", KEYS[1], KEYS[2])
+	return 1
+end
+local attempts = redis.call("INCR", KEYS[2])
+if attempts == 1 then
+	local ttl = redis.call("PTTL", KEYS[1])
+	if ttl > 0 then
+		redis.call("PEXPIRE", KEYS[2], ttl)
+	end
+end
+if attempts >= tonumber(ARGV[2]) then
+	redis.call("DEL", KEYS[1], KEYS[2])
+end
+return 0
+`)
+
+// ConsumeOTP reports whether otp is the active OTP for purpose and, if so,
+// deletes it so it cannot be used again. The check and the deletion are
+// atomic, and maxAttempts wrong guesses void the OTP.
+func (r *otpRepository) ConsumeOTP(purpose, phoneNumber, otp string, maxAttempts int) (bool, error) {
+	ctx := context.Background()
+	keys := []string{otpKey(purpose, phoneNumber), otpAttemptsKey(purpose, phoneNumber)}
+	ok, err := consumeOTPScript.Run(ctx, r.client, keys, otp, maxAttempts).Int()
+	if err != nil {
+		return false, err
+	}
+	return ok == 1, nil
+}
+
+// GetOTPAttempts returns the wrong guesses made at the active OTP for
+// purpose.
+func (r *otpRepository) GetOTPAttempts(purpose, phoneNumber string) (int, error) {
+	ctx := context.Background()
+	attempts, err := r.client.Get(ctx, otpAttemptsKey(purpose, phoneNumber)).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return attempts, err
 }
 
-func (r *otpRepository) GetOTP(phoneNumber string) (string, error) {
+// ResetOTPAttempts gives the active OTP for purpose its full attempt budget
+// back.
+func (r *otpRepository) ResetOTPAttempts(purpose, phoneNumber string) error {
 	ctx := context.Background()
-	return r.client.Get(ctx, "otp:"+phoneNumber).Result()
+	return r.client.Del(ctx, otpAttemptsKey(purpose, phoneNumber)).Err()
 }
 
 func (r *otpRepository) IncrementRequestCount(phoneNumber string, expiration time.Duration) (int, error) {
 	// Use database for rate limiting instead of Redis
 	since := time.Now().UTC().Add(-expiration)
+
+	// Requests made before an admin reset no longer count
+	resetAt, err := r.GetRequestCountResetAt(phoneNumber)
+	if err != nil {
+		return 0, err
+	}
+	if resetAt.After(since) {
+		since = resetAt
+	}
+
 	return r.GetRequestCount(phoneNumber, since)
 }
 
-func (r *otpRepository) RecordOTPRequest(phoneNumber string, successful bool) error {
+// IncrementIPRequestCount counts requests per client IP in a fixed window
+// that starts with the first request.
+func (r *otpRepository) IncrementIPRequestCount(ip string, expiration time.Duration) (int, error) {
+	ctx := context.Background()
+	key := "otp:ip:" + ip
+
+	count, err := r.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		if err := r.client.Expire(ctx, key, expiration).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return int(count), nil
+}
+
+func (r *otpRepository) RecordOTPRequest(phoneNumber, country string, successful bool) error {
 	otpRequest := &model.OTPRequest{
 		PhoneNumber: phoneNumber,
+		Country:     country,
 		RequestedAt: time.Now().UTC(),
 		Successful:  successful,
 	}
@@ -68,3 +203,45 @@ func (r *otpRepository) GetSuccessfulRequestCount(phoneNumber string, since time
 		Count(&count).Error
 	return int(count), err
 }
+
+func (r *otpRepository) GetRequestTimes(phoneNumber string, since time.Time) ([]time.Time, error) {
+	var times []time.Time
+	err := r.db.Model(&model.OTPRequest{}).
+		Where("phone_number = ? AND requested_at >= ?", phoneNumber, since.UTC()).
+		Order("requested_at").
+		Pluck("requested_at", &times).Error
+	return times, err
+}
+
+// ListRequests returns the OTP requests made for a number while a user
+// held it, oldest first.
+func (r *otpRepository) ListRequests(ownerships []model.PhoneOwnership) ([]model.OTPRequest, error) {
+	var requests []model.OTPRequest
+	if len(ownerships) == 0 {
+		return requests, nil
+	}
+	err := whereOwned(r.db, ownerships).
+		Order("requested_at").
+		Find(&requests).Error
+	return requests, err
+}
+
+// ResetRequestCount marks the current time as the start of the rate limit
+// window. The otp_requests history itself is left untouched for reporting.
+func (r *otpRepository) ResetRequestCount(phoneNumber string, expiration time.Duration) error {
+	ctx := context.Background()
+	now := time.Now().UTC().UnixNano()
+	return r.client.Set(ctx, "otp:reset:"+phoneNumber, now, expiration).Err()
+}
+
+func (r *otpRepository) GetRequestCountResetAt(phoneNumber string) (time.Time, error) {
+	ctx := context.Background()
+	nanos, err := r.client.Get(ctx, "otp:reset:"+phoneNumber).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, nanos).UTC(), nil
+}
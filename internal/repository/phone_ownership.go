@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"otp-auth-service/internal/model"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// whereOwned limits query to rows whose phone_number and requested_at fall
+// in one of ownerships. Numbers are recycled, so matching the number alone
+// would reach rows of other people.
+func whereOwned(query *gorm.DB, ownerships []model.PhoneOwnership) *gorm.DB {
+	conditions := make([]string, 0, len(ownerships))
+	args := make([]interface{}, 0, 3*len(ownerships))
+	for _, ownership := range ownerships {
+		condition := "phone_number = ?"
+		args = append(args, ownership.PhoneNumber)
+		if !ownership.From.IsZero() {
+			condition += " AND requested_at >= ?"
+			args = append(args, ownership.From.UTC())
+		}
+		if !ownership.Until.IsZero() {
+			condition += " AND requested_at < ?"
+			args = append(args, ownership.Until.UTC())
+		}
+		conditions = append(conditions, "("+condition+")")
+	}
+	return query.Where(strings.Join(conditions, " OR "), args...)
+}
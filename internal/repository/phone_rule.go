@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"otp-auth-service/internal/model"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	phoneRulesCacheKey = "phone_rules"
+	phoneRulesCacheTTL = 5 * time.Minute
+)
+
+type PhoneRuleRepository interface {
+	List() ([]model.PhoneRule, error)
+	Create(rule *model.PhoneRule) error
+	Update(rule *model.PhoneRule) error
+	Delete(id uint) error
+}
+
+type phoneRuleRepository struct {
+	client *redis.Client
+	db     *gorm.DB
+}
+
+func NewPhoneRuleRepository(client *redis.Client, db *gorm.DB) PhoneRuleRepository {
+	return &phoneRuleRepository{client: client, db: db}
+}
+
+// List returns every rule, served from Redis when cached. The cache is
+// dropped on every change so admins see their edits immediately.
+func (r *phoneRuleRepository) List() ([]model.PhoneRule, error) {
+	ctx := context.Background()
+
+	var rules []model.PhoneRule
+	cached, err := r.client.Get(ctx, phoneRulesCacheKey).Bytes()
+	if err == nil && json.Unmarshal(cached, &rules) == nil {
+		return rules, nil
+	}
+
+	if err := r.db.Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(rules); err == nil {
+		r.client.Set(ctx, phoneRulesCacheKey, data, phoneRulesCacheTTL)
+	}
+	return rules, nil
+}
+
+func (r *phoneRuleRepository) Create(rule *model.PhoneRule) error {
+	if err := r.db.Create(rule).Error; err != nil {
+		return err
+	}
+	return r.invalidate()
+}
+
+func (r *phoneRuleRepository) Update(rule *model.PhoneRule) error {
+	if err := r.db.Save(rule).Error; err != nil {
+		return err
+	}
+	return r.invalidate()
+}
+
+func (r *phoneRuleRepository) Delete(id uint) error {
+	result := r.db.Delete(&model.PhoneRule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return r.invalidate()
+}
+
+func (r *phoneRuleRepository) invalidate() error {
+	ctx := context.Background()
+	return r.client.Del(ctx, phoneRulesCacheKey).Err()
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"otp-auth-service/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(session *model.Session) error
+	FindByID(id string) (*model.Session, error)
+	ListActive(userID uint, seenSince time.Time) ([]model.Session, error)
+	ListAll(userID uint) ([]model.Session, error)
+	Touch(id, ipAddress string, at time.Time) error
+	UpdateAuthentication(id string, authTime time.Time, amr, acr string) error
+	Revoke(id string) error
+	RevokeAllForUser(userID uint) error
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(session *model.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sessionRepository) FindByID(id string) (*model.Session, error) {
+	var session model.Session
+	err := r.db.Where("id = ?", id).First(&session).Error
+	return &session, err
+}
+
+// ListActive returns the user's unrevoked sessions seen since seenSince,
+// most recently used first.
+func (r *sessionRepository) ListActive(userID uint, seenSince time.Time) ([]model.Session, error) {
+	var sessions []model.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND last_seen_at > ?", userID, seenSince).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// ListAll returns every session of the user, including ended ones, newest
+// first.
+func (r *sessionRepository) ListAll(userID uint) ([]model.Session, error) {
+	var sessions []model.Session
+	err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) Touch(id, ipAddress string, at time.Time) error {
+	return r.db.Model(&model.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"last_seen_at": at, "ip_address": ipAddress}).Error
+}
+
+// UpdateAuthentication records a re-authentication in an active session.
+// It returns gorm.ErrRecordNotFound when the session is gone or revoked.
+func (r *sessionRepository) UpdateAuthentication(id string, authTime time.Time, amr, acr string) error {
+	result := r.db.Model(&model.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"auth_time": authTime, "amr": amr, "acr": acr})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *sessionRepository) Revoke(id string) error {
+	return r.db.Model(&model.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now().UTC()).Error
+}
+
+func (r *sessionRepository) RevokeAllForUser(userID uint) error {
+	return r.db.Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now().UTC()).Error
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"otp-auth-service/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TrustedDeviceRepository interface {
+	Create(device *model.TrustedDevice) error
+	// FindActive returns the unrevoked, unexpired device with id.
+	FindActive(id string) (*model.TrustedDevice, error)
+	ListActive(userID uint) ([]model.TrustedDevice, error)
+	ListAll(userID uint) ([]model.TrustedDevice, error)
+	TouchLastUsed(id string, at time.Time) error
+	// Revoke returns gorm.ErrRecordNotFound when the user has no active
+	// device with id.
+	Revoke(userID uint, id string) error
+	RevokeAllForUser(userID uint) error
+}
+
+type trustedDeviceRepository struct {
+	db *gorm.DB
+}
+
+func NewTrustedDeviceRepository(db *gorm.DB) TrustedDeviceRepository {
+	return &trustedDeviceRepository{db: db}
+}
+
+func (r *trustedDeviceRepository) Create(device *model.TrustedDevice) error {
+	return r.db.Create(device).Error
+}
+
+func (r *trustedDeviceRepository) FindActive(id string) (*model.TrustedDevice, error) {
+	var device model.TrustedDevice
+	err := r.active().Where("id = ?", id).First(&device).Error
+	return &device, err
+}
+
+// ListActive returns the user's trusted devices, most recently trusted
+// first.
+func (r *trustedDeviceRepository) ListActive(userID uint) ([]model.TrustedDevice, error) {
+	var devices []model.TrustedDevice
+	err := r.active().Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&devices).Error
+	return devices, err
+}
+
+// ListAll returns every trusted device of the user, including revoked and
+// expired ones, most recently trusted first.
+func (r *trustedDeviceRepository) ListAll(userID uint) ([]model.TrustedDevice, error) {
+	var devices []model.TrustedDevice
+	err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&devices).Error
+	return devices, err
+}
+
+func (r *trustedDeviceRepository) TouchLastUsed(id string, at time.Time) error {
+	return r.db.Model(&model.TrustedDevice{}).
+		Where("id = ?", id).
+		Update("last_used_at", at).Error
+}
+
+func (r *trustedDeviceRepository) Revoke(userID uint, id string) error {
+	result := r.active().Model(&model.TrustedDevice{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("revoked_at", time.Now().UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *trustedDeviceRepository) RevokeAllForUser(userID uint) error {
+	return r.db.Model(&model.TrustedDevice{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now().UTC()).Error
+}
+
+func (r *trustedDeviceRepository) active() *gorm.DB {
+	return r.db.Where("revoked_at IS NULL AND expires_at > ?", time.Now().UTC())
+}
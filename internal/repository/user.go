@@ -2,6 +2,8 @@ package repository
 
 import (
 	"otp-auth-service/internal/model"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -11,6 +13,17 @@ type UserRepository interface {
 	FindByPhoneNumber(phoneNumber string) (*model.User, error)
 	FindByID(id uint) (*model.User, error)
 	FindAll(offset, limit int, search string) ([]model.User, int64, error)
+	ChangePhoneNumber(userID uint, oldPhoneNumber, newPhoneNumber string) error
+	UpdateRoles(userID uint, roles []string) error
+	UpdateMetadata(userID uint, metadata string) error
+	UpdateProfile(userID uint, fields map[string]interface{}) error
+	UpdateStatus(userID uint, status, reason string, suspendedUntil *time.Time, changedAt time.Time) error
+	ListPhoneNumberHistory(userID uint) ([]model.PhoneNumberHistory, error)
+	LastReleasedAt(phoneNumber string, before time.Time) (time.Time, error)
+	SoftDelete(userID uint) error
+	Restore(userID uint) error
+	FindDeletedBefore(cutoff time.Time, limit int) ([]model.User, error)
+	Purge(userID uint, ownerships []model.PhoneOwnership) error
 	HealthCheck() error
 }
 
@@ -26,9 +39,11 @@ func (r *userRepository) Create(user *model.User) error {
 	return r.db.Create(user).Error
 }
 
+// FindByPhoneNumber also finds deleted users that have not been purged yet,
+// whose numbers are still taken.
 func (r *userRepository) FindByPhoneNumber(phoneNumber string) (*model.User, error) {
 	var user model.User
-	err := r.db.Where("phone_number = ?", phoneNumber).First(&user).Error
+	err := r.db.Unscoped().Where("phone_number = ?", phoneNumber).First(&user).Error
 	return &user, err
 }
 
@@ -56,6 +71,185 @@ func (r *userRepository) FindAll(offset, limit int, search string) ([]model.User
 	return users, total, err
 }
 
+// ChangePhoneNumber swaps the user's number and records the old one in a
+// single transaction. It returns gorm.ErrRecordNotFound when the user no
+// longer has oldPhoneNumber and gorm.ErrDuplicatedKey when newPhoneNumber
+// belongs to someone else.
+func (r *userRepository) ChangePhoneNumber(userID uint, oldPhoneNumber, newPhoneNumber string) error {
+	now := time.Now().UTC()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.User{}).
+			Where("id = ? AND phone_number = ?", userID, oldPhoneNumber).
+			Update("phone_number", newPhoneNumber)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Create(&model.PhoneNumberHistory{
+			UserID:         userID,
+			OldPhoneNumber: oldPhoneNumber,
+			NewPhoneNumber: newPhoneNumber,
+			ChangedAt:      now,
+		}).Error
+	})
+}
+
+// UpdateRoles returns gorm.ErrRecordNotFound when the user does not exist.
+func (r *userRepository) UpdateRoles(userID uint, roles []string) error {
+	result := r.db.Model(&model.User{}).
+		Where("id = ?", userID).
+		Update("roles", strings.Join(roles, " "))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateMetadata replaces the user's metadata with a JSON object. It
+// returns gorm.ErrRecordNotFound when the user does not exist.
+func (r *userRepository) UpdateMetadata(userID uint, metadata string) error {
+	result := r.db.Model(&model.User{}).
+		Where("id = ?", userID).
+		Update("metadata", metadata)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateProfile sets the given profile columns and updated_at. It returns
+// gorm.ErrRecordNotFound when the user does not exist.
+func (r *userRepository) UpdateProfile(userID uint, fields map[string]interface{}) error {
+	result := r.db.Model(&model.User{}).
+		Where("id = ?", userID).
+		Updates(fields)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateStatus returns gorm.ErrRecordNotFound when the user does not exist.
+func (r *userRepository) UpdateStatus(userID uint, status, reason string, suspendedUntil *time.Time, changedAt time.Time) error {
+	result := r.db.Model(&model.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"status":            status,
+			"status_reason":     reason,
+			"status_changed_at": changedAt,
+			"suspended_until":   suspendedUntil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListPhoneNumberHistory returns the user's phone number changes, oldest
+// first.
+func (r *userRepository) ListPhoneNumberHistory(userID uint) ([]model.PhoneNumberHistory, error) {
+	var history []model.PhoneNumberHistory
+	err := r.db.Where("user_id = ?", userID).
+		Order("changed_at").
+		Find(&history).Error
+	return history, err
+}
+
+// LastReleasedAt returns when phoneNumber was last changed away from at or
+// before before, or the zero time when nobody had released it by then.
+func (r *userRepository) LastReleasedAt(phoneNumber string, before time.Time) (time.Time, error) {
+	var history []model.PhoneNumberHistory
+	err := r.db.Where("old_phone_number = ? AND changed_at <= ?", phoneNumber, before.UTC()).
+		Order("changed_at DESC").
+		Limit(1).
+		Find(&history).Error
+	if err != nil || len(history) == 0 {
+		return time.Time{}, err
+	}
+	return history[0].ChangedAt, nil
+}
+
+// SoftDelete marks the user deleted. It returns gorm.ErrRecordNotFound when
+// the user does not exist or is already deleted.
+func (r *userRepository) SoftDelete(userID uint) error {
+	result := r.db.Delete(&model.User{}, userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Restore undoes SoftDelete. It returns gorm.ErrRecordNotFound when the
+// user is not pending deletion.
+func (r *userRepository) Restore(userID uint) error {
+	result := r.db.Unscoped().Model(&model.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", userID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindDeletedBefore returns up to limit users deleted before cutoff, oldest
+// deletion first.
+func (r *userRepository) FindDeletedBefore(cutoff time.Time, limit int) ([]model.User, error) {
+	var users []model.User
+	err := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff.UTC()).
+		Order("deleted_at").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// Purge removes a deleted user for good. Sessions, refresh tokens, trusted
+// devices and phone history go with the row; otp_requests rows made while
+// the user held a number are kept for reporting without the number.
+func (r *userRepository) Purge(userID uint, ownerships []model.PhoneOwnership) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if len(ownerships) > 0 {
+			err := whereOwned(tx.Model(&model.OTPRequest{}), ownerships).
+				Update("phone_number", "").Error
+			if err != nil {
+				return err
+			}
+		}
+
+		result := tx.Unscoped().
+			Where("id = ? AND deleted_at IS NOT NULL", userID).
+			Delete(&model.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
 func (r *userRepository) HealthCheck() error {
 	sqlDB, err := r.db.DB()
 	if err != nil {
@@ -0,0 +1,29 @@
+package sender
+
+import "log"
+
+const (
+	ChannelSMS      = "sms"
+	ChannelVoice    = "voice"
+	ChannelWhatsApp = "whatsapp"
+)
+
+// Sender delivers an OTP to a phone number over one channel.
+type Sender interface {
+	Send(phoneNumber, otp string) error
+}
+
+type consoleSender struct {
+	channel string
+}
+
+// NewConsoleSender returns a Sender that only logs the OTP. It stands in for
+// a real provider during development.
+func NewConsoleSender(channel string) Sender {
+	return &consoleSender{channel: channel}
+}
+
+func (s *consoleSender) Send(phoneNumber, otp string) error {
+	log.Printf("OTP for %s via %s: %s", phoneNumber, s.channel, otp)
+	return nil
+}
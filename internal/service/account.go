@@ -0,0 +1,234 @@
+package service
+
+import (
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/repository"
+	"time"
+)
+
+const (
+	auditActionPurgeUser = "user.purge"
+	// purgeActor records purges done by the service itself.
+	purgeActor     = "system"
+	purgeBatchSize = 100
+)
+
+// StartAccountDeletion sends an account-deletion OTP to the user's current
+// number. It is rate limited but never gated behind a challenge, the phone
+// lists or the country policy.
+func (s *authService) StartAccountDeletion(userID uint) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	number, err := s.phoneParser.Parse(user.PhoneNumber)
+	if err != nil {
+		return err
+	}
+	return s.sendAccountOTP(model.OTPPurposeDeletion, number)
+}
+
+// DeleteAccount checks an account-deletion OTP, signs the user out
+// everywhere and marks the account deleted. The account is purged once
+// the deletion grace period has passed.
+func (s *authService) DeleteAccount(userID uint, otp string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkOTP(model.OTPPurposeDeletion, user.PhoneNumber, otp); err != nil {
+		return err
+	}
+
+	if err := s.revokeAllTokens(user.ID); err != nil {
+		return err
+	}
+	return s.userRepo.SoftDelete(user.ID)
+}
+
+type AccountService interface {
+	Export(userID uint) (*model.AccountExport, error)
+	PurgeDeleted() (int, error)
+}
+
+type accountService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	deviceRepo  repository.TrustedDeviceRepository
+	otpRepo     repository.OTPRepository
+	auditRepo   repository.AuditRepository
+	accountCfg  config.Account
+}
+
+func NewAccountService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, deviceRepo repository.TrustedDeviceRepository, otpRepo repository.OTPRepository, auditRepo repository.AuditRepository, accountCfg config.Account) AccountService {
+	return &accountService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		deviceRepo:  deviceRepo,
+		otpRepo:     otpRepo,
+		auditRepo:   auditRepo,
+		accountCfg:  accountCfg,
+	}
+}
+
+// Export collects the user's profile, phone history, sessions, trusted
+// devices and the OTP requests made for their numbers while they held them.
+func (s *accountService) Export(userID uint) (*model.AccountExport, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := user.MetadataMap()
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := s.userRepo.ListPhoneNumberHistory(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.sessionRepo.ListAll(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	sessionExports := make([]model.SessionExport, 0, len(sessions))
+	for _, session := range sessions {
+		sessionExports = append(sessionExports, model.SessionExport{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			AuthTime:   session.AuthTime,
+			AMR:        session.AMRList(),
+			ACR:        session.ACR,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			RevokedAt:  session.RevokedAt,
+		})
+	}
+
+	devices, err := s.deviceRepo.ListAll(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	deviceExports := make([]model.TrustedDeviceExport, 0, len(devices))
+	for _, device := range devices {
+		deviceExports = append(deviceExports, model.TrustedDeviceExport{
+			TrustedDeviceResponse: model.TrustedDeviceResponse{
+				ID:         device.ID,
+				Name:       device.Name,
+				UserAgent:  device.UserAgent,
+				CreatedAt:  device.CreatedAt,
+				LastUsedAt: device.LastUsedAt,
+				ExpiresAt:  device.ExpiresAt,
+			},
+			RevokedAt: device.RevokedAt,
+		})
+	}
+
+	ownerships, err := s.phoneOwnerships(user, history)
+	if err != nil {
+		return nil, err
+	}
+	requests, err := s.otpRepo.ListRequests(ownerships)
+	if err != nil {
+		return nil, err
+	}
+	requestExports := make([]model.OTPRequestResponse, 0, len(requests))
+	for _, request := range requests {
+		requestExports = append(requestExports, model.OTPRequestResponse{
+			ID:          request.ID,
+			PhoneNumber: request.PhoneNumber,
+			Country:     request.Country,
+			RequestedAt: request.RequestedAt,
+			Successful:  request.Successful,
+		})
+	}
+
+	if history == nil {
+		history = []model.PhoneNumberHistory{}
+	}
+	return &model.AccountExport{
+		ExportedAt:         time.Now().UTC(),
+		Profile:            user.Response(),
+		Attributes:         attributes,
+		PhoneNumberHistory: history,
+		Sessions:           sessionExports,
+		TrustedDevices:     deviceExports,
+		OTPRequests:        requestExports,
+	}, nil
+}
+
+// PurgeDeleted removes the accounts deleted longer than the grace period
+// ago and strips their numbers from the OTP requests made while they held
+// them. It reports
+// how many accounts were purged.
+func (s *accountService) PurgeDeleted() (int, error) {
+	cutoff := time.Now().UTC().Add(-s.accountCfg.DeletionGrace)
+
+	purged := 0
+	for {
+		users, err := s.userRepo.FindDeletedBefore(cutoff, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, user := range users {
+			history, err := s.userRepo.ListPhoneNumberHistory(user.ID)
+			if err != nil {
+				return purged, err
+			}
+			ownerships, err := s.phoneOwnerships(&user, history)
+			if err != nil {
+				return purged, err
+			}
+			if err := s.userRepo.Purge(user.ID, ownerships); err != nil {
+				return purged, err
+			}
+			purged++
+
+			err = s.auditRepo.Record(&model.AuditLog{
+				Actor:     purgeActor,
+				Action:    auditActionPurgeUser,
+				Target:    userTarget(user.ID),
+				Reason:    "deletion grace period ended",
+				CreatedAt: time.Now().UTC(),
+			})
+			if err != nil {
+				return purged, err
+			}
+		}
+
+		if len(users) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// phoneOwnerships lists the periods during which the user held each of
+// their numbers, through the phone changes in history, which must be
+// ordered oldest first. A number's OTP is requested before it is taken, at
+// signup or for a phone change, so each period opens when the previous
+// holder released the number rather than when the user took it.
+func (s *accountService) phoneOwnerships(user *model.User, history []model.PhoneNumberHistory) ([]model.PhoneOwnership, error) {
+	ownerships := make([]model.PhoneOwnership, 0, len(history)+1)
+	takenAt := user.CreatedAt
+	for i := 0; i <= len(history); i++ {
+		number, until := user.PhoneNumber, time.Time{}
+		if i < len(history) {
+			number, until = history[i].OldPhoneNumber, history[i].ChangedAt
+		}
+
+		from, err := s.userRepo.LastReleasedAt(number, takenAt)
+		if err != nil {
+			return nil, err
+		}
+		ownerships = append(ownerships, model.PhoneOwnership{PhoneNumber: number, From: from, Until: until})
+		takenAt = until
+	}
+	return ownerships, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/repository"
+	"slices"
+	"testing"
+	"time"
+)
+
+type exportUserRepo struct {
+	repository.UserRepository
+	users   map[uint]*model.User
+	history map[uint][]model.PhoneNumberHistory
+}
+
+func (r *exportUserRepo) FindByID(id uint) (*model.User, error) {
+	return r.users[id], nil
+}
+
+func (r *exportUserRepo) ListPhoneNumberHistory(userID uint) ([]model.PhoneNumberHistory, error) {
+	return r.history[userID], nil
+}
+
+func (r *exportUserRepo) LastReleasedAt(phoneNumber string, before time.Time) (time.Time, error) {
+	var released time.Time
+	for _, history := range r.history {
+		for _, change := range history {
+			if change.OldPhoneNumber == phoneNumber && !change.ChangedAt.After(before) && change.ChangedAt.After(released) {
+				released = change.ChangedAt
+			}
+		}
+	}
+	return released, nil
+}
+
+type exportSessionRepo struct{ repository.SessionRepository }
+
+func (exportSessionRepo) ListAll(uint) ([]model.Session, error) { return nil, nil }
+
+type exportDeviceRepo struct {
+	repository.TrustedDeviceRepository
+}
+
+func (exportDeviceRepo) ListAll(uint) ([]model.TrustedDevice, error) { return nil, nil }
+
+// exportOTPRepo filters requests the way whereOwned does in SQL.
+type exportOTPRepo struct {
+	repository.OTPRepository
+	requests []model.OTPRequest
+}
+
+func (r *exportOTPRepo) ListRequests(ownerships []model.PhoneOwnership) ([]model.OTPRequest, error) {
+	var matched []model.OTPRequest
+	for _, request := range r.requests {
+		for _, ownership := range ownerships {
+			if ownership.Covers(request.PhoneNumber, request.RequestedAt) {
+				matched = append(matched, request)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+func TestExportSkipsRequestsOfRecycledNumber(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	carolChangedAt := start.Add(10 * 24 * time.Hour)
+	changedAt := start.Add(30 * 24 * time.Hour)
+	recycledAt := changedAt.Add(60 * 24 * time.Hour)
+
+	// Carol gave up +1666 before Alice moved to it from +1555, and +1555
+	// was later given to Bob
+	users := &exportUserRepo{
+		users: map[uint]*model.User{
+			1: {ID: 1, PhoneNumber: "+1666", CreatedAt: start},
+			2: {ID: 2, PhoneNumber: "+1555", CreatedAt: recycledAt},
+			3: {ID: 3, PhoneNumber: "+1777", CreatedAt: start.Add(-24 * time.Hour)},
+		},
+		history: map[uint][]model.PhoneNumberHistory{
+			1: {{UserID: 1, OldPhoneNumber: "+1555", NewPhoneNumber: "+1666", ChangedAt: changedAt}},
+			3: {{UserID: 3, OldPhoneNumber: "+1666", NewPhoneNumber: "+1777", ChangedAt: carolChangedAt}},
+		},
+	}
+	otps := &exportOTPRepo{requests: []model.OTPRequest{
+		// Alice's signup
+		{ID: 1, PhoneNumber: "+1555", RequestedAt: start.Add(-5 * time.Minute)},
+		{ID: 2, PhoneNumber: "+1555", RequestedAt: start.Add(time.Hour)},
+		// Alice's phone change to +1666
+		{ID: 3, PhoneNumber: "+1666", RequestedAt: changedAt.Add(-time.Minute)},
+		{ID: 4, PhoneNumber: "+1666", RequestedAt: changedAt.Add(time.Hour)},
+		// Bob's signup
+		{ID: 5, PhoneNumber: "+1555", RequestedAt: recycledAt.Add(-5 * time.Minute)},
+		{ID: 6, PhoneNumber: "+1555", RequestedAt: recycledAt.Add(time.Hour)},
+		// Carol's login while she held +1666
+		{ID: 7, PhoneNumber: "+1666", RequestedAt: start.Add(time.Hour)},
+	}}
+	svc := NewAccountService(users, exportSessionRepo{}, exportDeviceRepo{}, otps, nil, config.Account{})
+
+	tests := []struct {
+		userID uint
+		want   []uint
+	}{
+		{userID: 1, want: []uint{1, 2, 3, 4}},
+		{userID: 2, want: []uint{5, 6}},
+		{userID: 3, want: []uint{7}},
+	}
+	for _, tt := range tests {
+		export, err := svc.Export(tt.userID)
+		if err != nil {
+			t.Fatalf("Export(%d): %v", tt.userID, err)
+		}
+		var got []uint
+		for _, request := range export.OTPRequests {
+			got = append(got, request.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Export(%d) requests = %v, want %v", tt.userID, got, tt.want)
+		}
+	}
+}
+
+func TestPhoneOwnerships(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	released := created.Add(12 * time.Hour)
+	first := created.Add(24 * time.Hour)
+	second := first.Add(24 * time.Hour)
+	user := &model.User{ID: 1, PhoneNumber: "+3", CreatedAt: created}
+	users := &exportUserRepo{history: map[uint][]model.PhoneNumberHistory{
+		1: {
+			{OldPhoneNumber: "+1", NewPhoneNumber: "+2", ChangedAt: first},
+			{OldPhoneNumber: "+2", NewPhoneNumber: "+3", ChangedAt: second},
+		},
+		// Someone else held +2 until before the user took it
+		2: {{OldPhoneNumber: "+2", NewPhoneNumber: "+4", ChangedAt: released}},
+	}}
+	svc := &accountService{userRepo: users}
+
+	got, err := svc.phoneOwnerships(user, users.history[1])
+	if err != nil {
+		t.Fatalf("phoneOwnerships: %v", err)
+	}
+	want := []model.PhoneOwnership{
+		{PhoneNumber: "+1", Until: first},
+		{PhoneNumber: "+2", From: released, Until: second},
+		{PhoneNumber: "+3"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("phoneOwnerships = %+v, want %+v", got, want)
+	}
+}
@@ -1,89 +1,375 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"math/big"
+	"otp-auth-service/internal/challenge"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/enrich"
 	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/phone"
 	"otp-auth-service/internal/repository"
+	"otp-auth-service/internal/sender"
+	"otp-auth-service/internal/token"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
 )
 
 type AuthService interface {
-	RequestOTP(phoneNumber string) error
-	VerifyOTP(phoneNumber, otp string) (string, error)
+	RequestOTP(phoneNumber, clientIP, challengeSolution string) error
+	VerifyOTP(phoneNumber, otp string, client model.SessionInfo, trustDevice bool) (*model.TokenPair, error)
+	StartReauthentication(userID uint) error
+	Reauthenticate(userID uint, sessionID, otp string) (*model.TokenPair, error)
+	DeviceLogin(phoneNumber, deviceToken string, client model.SessionInfo) (*model.TokenPair, error)
+	Login(phoneNumber, otp string, client model.SessionInfo) (*model.User, string, error)
+	Refresh(refreshToken string, client model.SessionInfo) (*model.TokenPair, error)
+	Logout(userID uint, sessionID, jti string, expiresAt time.Time, refreshToken string) error
+	StartPhoneChange(userID uint, newPhoneNumber, clientIP, challengeSolution string) error
+	ConfirmPhoneChange(userID uint, newPhoneNumber, otp, oldOTP string, client model.SessionInfo) (*model.TokenPair, error)
+	StartAccountDeletion(userID uint) error
+	DeleteAccount(userID uint, otp string) error
 	GenerateJWT(user *model.User) (string, error)
+	IssueAccessToken(user *model.User, opts TokenOptions) (string, error)
+}
+
+// TokenOptions customizes an access token beyond the user's identity.
+type TokenOptions struct {
+	SessionID string
+	// Scope and ClientID are set for tokens issued to OAuth clients. Other
+	// tokens carry the user's roles and the scopes those grant.
+	Scope    string
+	ClientID string
+	// DPoPJKT binds the token to a DPoP key.
+	DPoPJKT string
+	// AuthTime, AMR and ACR describe the authentication behind the token.
+	// Tokens with a zero AuthTime never satisfy RequireAuthentication.
+	AuthTime time.Time
+	AMR      []string
+	ACR      string
 }
 
 type authService struct {
-	userRepo   repository.UserRepository
-	otpRepo    repository.OTPRepository
-	jwtSecret  string
-	otpExpiry  time.Duration
-	rateLimit  int
-	rateWindow time.Duration
+	userRepo      repository.UserRepository
+	otpRepo       repository.OTPRepository
+	phoneRuleRepo repository.PhoneRuleRepository
+	tokenRepo     repository.TokenRepository
+	refreshRepo   repository.RefreshTokenRepository
+	sessionRepo   repository.SessionRepository
+	deviceRepo    repository.TrustedDeviceRepository
+	challenge     challenge.Verifier
+	phoneParser   *phone.Parser
+	senders       map[string]sender.Sender
+	otpCfg        config.OTP
+	phoneCfg      config.Phone
+	deviceCfg     config.Device
+	jwtCfg        config.JWT
+	keys          *token.KeySet
+	enricher      enrich.Enricher
 }
 
-func NewAuthService(userRepo repository.UserRepository, otpRepo repository.OTPRepository) AuthService {
+// NewAuthService creates the OTP login service. verifier may be nil, in which
+// case no challenge is ever required. senders maps channel names to the
+// Sender used for them. enricher may be nil when tokens carry no custom
+// claims.
+func NewAuthService(userRepo repository.UserRepository, otpRepo repository.OTPRepository, phoneRuleRepo repository.PhoneRuleRepository, tokenRepo repository.TokenRepository, refreshRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository, deviceRepo repository.TrustedDeviceRepository, verifier challenge.Verifier, phoneParser *phone.Parser, senders map[string]sender.Sender, otpCfg config.OTP, phoneCfg config.Phone, deviceCfg config.Device, jwtCfg config.JWT, keys *token.KeySet, enricher enrich.Enricher) AuthService {
 	return &authService{
-		userRepo:   userRepo,
-		otpRepo:    otpRepo,
-		otpExpiry:  2 * time.Minute,
-		rateLimit:  3,
-		rateWindow: 10 * time.Minute,
+		userRepo:      userRepo,
+		otpRepo:       otpRepo,
+		phoneRuleRepo: phoneRuleRepo,
+		tokenRepo:     tokenRepo,
+		refreshRepo:   refreshRepo,
+		sessionRepo:   sessionRepo,
+		deviceRepo:    deviceRepo,
+		challenge:     verifier,
+		phoneParser:   phoneParser,
+		senders:       senders,
+		otpCfg:        otpCfg,
+		phoneCfg:      phoneCfg,
+		deviceCfg:     deviceCfg,
+		jwtCfg:        jwtCfg,
+		keys:          keys,
+		enricher:      enricher,
+	}
+}
+
+func (s *authService) RequestOTP(rawPhoneNumber, clientIP, challengeSolution string) error {
+	// Normalize to E.164 so every spelling of a number maps to one user
+	number, err := s.phoneParser.Parse(rawPhoneNumber)
+	if err != nil {
+		return err
+	}
+
+	// Deleted, suspended and banned accounts cannot log in
+	if user, err := s.userRepo.FindByPhoneNumber(number.E164); err == nil {
+		if err := checkAccountStatus(user); err != nil {
+			return err
+		}
+	}
+
+	return s.sendOTP(model.OTPPurposeLogin, number, func(count int) error {
+		return s.checkChallenge(count, clientIP, challengeSolution)
+	})
+}
+
+// sendOTP applies the phone lists, country restrictions and rate limits to
+// number and delivers a fresh OTP scoped to purpose. gate, when set, is
+// called with the current request count after rate limiting passes.
+func (s *authService) sendOTP(purpose string, number *phone.Number, gate func(count int) error) error {
+	phoneNumber := number.E164
+	region := number.Region
+	policy := resolvePolicy(s.otpCfg, region)
+
+	// Consult the admin-managed phone lists
+	rules, err := s.phoneRuleRepo.List()
+	if err != nil {
+		return err
+	}
+
+	var list string
+	if rule := matchPhoneRule(rules, phoneNumber); rule != nil {
+		list = rule.List
+	}
+
+	if list == model.PhoneListDeny {
+		s.otpRepo.RecordOTPRequest(phoneNumber, region, false)
+		return ErrPhoneBlocked
+	}
+
+	if list == model.PhoneListTest {
+		return s.storeTestOTP(purpose, number, policy)
+	}
+
+	if !countryAllowed(s.otpCfg, region) {
+		s.otpRepo.RecordOTPRequest(phoneNumber, region, false)
+		return ErrCountryNotAllowed
+	}
+
+	// Allowlisted numbers skip rate limiting and challenges
+	if list != model.PhoneListAllow {
+		// Check rate limiting
+		count, err := s.otpRepo.IncrementRequestCount(phoneNumber, policy.rateWindow)
+		if err != nil {
+			return err
+		}
+
+		if count > policy.rateLimit {
+			// Record failed request due to rate limiting
+			s.otpRepo.RecordOTPRequest(phoneNumber, region, false)
+			return ErrRateLimitExceeded
+		}
+
+		// Step up to a challenge once the soft thresholds are crossed
+		if gate != nil {
+			if err := gate(count); err != nil {
+				// Asking for a challenge is not a request; recording it
+				// would use up the caller's quota before they could answer
+				var challengeErr *ChallengeRequiredError
+				if !errors.As(err, &challengeErr) {
+					s.otpRepo.RecordOTPRequest(phoneNumber, region, false)
+				}
+				return err
+			}
+		}
 	}
+
+	return s.issueOTP(purpose, number, policy)
 }
 
-func (s *authService) RequestOTP(phoneNumber string) error {
-	// Check rate limiting
-	count, err := s.otpRepo.IncrementRequestCount(phoneNumber, s.rateWindow)
+// sendAccountOTP delivers an OTP confirming an action on the user's own
+// account. Users must be able to delete their account even after their
+// number is denied or their country stops being served, so only the rate
+// limit applies.
+func (s *authService) sendAccountOTP(purpose string, number *phone.Number) error {
+	policy := resolvePolicy(s.otpCfg, number.Region)
+
+	rules, err := s.phoneRuleRepo.List()
 	if err != nil {
 		return err
 	}
+	if rule := matchPhoneRule(rules, number.E164); rule != nil && rule.List == model.PhoneListTest {
+		return s.storeTestOTP(purpose, number, policy)
+	}
 
-	if count > s.rateLimit {
-		// Record failed request due to rate limiting
-		s.otpRepo.RecordOTPRequest(phoneNumber, false)
-		return fmt.Errorf("rate limit exceeded")
+	count, err := s.otpRepo.IncrementRequestCount(number.E164, policy.rateWindow)
+	if err != nil {
+		return err
+	}
+	if count > policy.rateLimit {
+		s.otpRepo.RecordOTPRequest(number.E164, number.Region, false)
+		return ErrRateLimitExceeded
 	}
 
+	return s.issueOTP(purpose, number, policy)
+}
+
+// storeTestOTP gives a test number the fixed code; nothing is sent.
+func (s *authService) storeTestOTP(purpose string, number *phone.Number, policy otpPolicy) error {
+	phoneNumber := number.E164
+	region := number.Region
+
+	if s.otpCfg.TestCode == "" {
+		return fmt.Errorf("no OTP configured for test number %s", phoneNumber)
+	}
+	if err := s.otpRepo.StoreOTP(purpose, phoneNumber, s.otpCfg.TestCode, policy.expiry); err != nil {
+		s.otpRepo.RecordOTPRequest(phoneNumber, region, false)
+		return err
+	}
+	s.otpRepo.RecordOTPRequest(phoneNumber, region, true)
+	return nil
+}
+
+// issueOTP generates, stores and delivers a fresh OTP once the checks for
+// the request have passed.
+func (s *authService) issueOTP(purpose string, number *phone.Number, policy otpPolicy) error {
+	phoneNumber := number.E164
+	region := number.Region
+
 	// Generate OTP
-	otp, err := generateOTP(6)
+	otp, err := generateOTP(policy.length)
 	if err != nil {
 		// Record failed request due to OTP generation error
-		s.otpRepo.RecordOTPRequest(phoneNumber, false)
+		s.otpRepo.RecordOTPRequest(phoneNumber, region, false)
 		return err
 	}
 
 	// Store OTP
-	err = s.otpRepo.StoreOTP(phoneNumber, otp, s.otpExpiry)
+	err = s.otpRepo.StoreOTP(purpose, phoneNumber, otp, policy.expiry)
 	if err != nil {
 		// Record failed request due to storage error
-		s.otpRepo.RecordOTPRequest(phoneNumber, false)
+		s.otpRepo.RecordOTPRequest(phoneNumber, region, false)
 		return err
 	}
 
-	// Record successful request
-	s.otpRepo.RecordOTPRequest(phoneNumber, true)
+	// Deliver over the first channel that works
+	if err := s.deliver(phoneNumber, otp, policy.channels); err != nil {
+		s.otpRepo.RecordOTPRequest(phoneNumber, region, false)
+		return err
+	}
 
-	// Print OTP to console (instead of sending SMS)
-	fmt.Printf("OTP for %s: %s\n", phoneNumber, otp)
+	// Record successful request
+	s.otpRepo.RecordOTPRequest(phoneNumber, region, true)
 
 	return nil
 }
 
-func (s *authService) VerifyOTP(phoneNumber, otp string) (string, error) {
-	// Get stored OTP
-	storedOTP, err := s.otpRepo.GetOTP(phoneNumber)
+// deliver tries each channel in order and stops at the first success.
+func (s *authService) deliver(phoneNumber, otp string, channels []string) error {
+	var errs []error
+	for _, channel := range channels {
+		channelSender, ok := s.senders[channel]
+		if !ok {
+			errs = append(errs, fmt.Errorf("no sender for channel %q", channel))
+			continue
+		}
+		err := channelSender.Send(phoneNumber, otp)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+	}
+	return fmt.Errorf("%w: %v", ErrDeliveryFailed, errors.Join(errs...))
+}
+
+// checkChallenge requires a solved challenge when the phone or the client
+// IP already made as many requests as its soft threshold allows. Both
+// counts exclude the current request; phoneCount is the number of earlier
+// requests in the rate window.
+func (s *authService) checkChallenge(phoneCount int, clientIP, solution string) error {
+	if s.challenge == nil {
+		return nil
+	}
+
+	cfg := s.otpCfg.Challenge
+	required := cfg.PhoneThreshold > 0 && phoneCount >= cfg.PhoneThreshold
+
+	if cfg.IPThreshold > 0 && clientIP != "" {
+		ipCount, err := s.otpRepo.IncrementIPRequestCount(clientIP, cfg.IPWindow)
+		if err != nil {
+			return err
+		}
+		// The IP counter already includes this request
+		if earlier := ipCount - 1; earlier >= cfg.IPThreshold {
+			required = true
+		}
+	}
+
+	if !required {
+		return nil
+	}
+
+	if solution != "" {
+		err := s.challenge.Verify(solution, clientIP)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, challenge.ErrInvalidSolution) {
+			return err
+		}
+	}
+
+	next, err := s.challenge.Issue()
+	if err != nil {
+		return err
+	}
+	return &ChallengeRequiredError{Challenge: next, Failed: solution != ""}
+}
+
+// VerifyOTP checks a login OTP and starts a session. With trustDevice, and
+// trusted devices enabled, it also registers the client as a trusted device
+// and returns its device token with the tokens.
+func (s *authService) VerifyOTP(rawPhoneNumber, otp string, client model.SessionInfo, trustDevice bool) (*model.TokenPair, error) {
+	user, err := s.authenticate(rawPhoneNumber, otp)
 	if err != nil {
-		return "", fmt.Errorf("invalid or expired OTP")
+		return nil, err
 	}
 
+	// Start a session and issue its access and refresh tokens
+	tokens, err := s.startSession(user, client, otpAuthentication)
+	if err != nil {
+		return nil, err
+	}
+
+	if trustDevice && s.deviceCfg.TrustTTL > 0 {
+		tokens.DeviceToken, err = s.trustDevice(user, client)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+// Login checks a login OTP like VerifyOTP and starts a session, but leaves
+// issuing tokens to the caller. It returns the user and the session ID.
+func (s *authService) Login(rawPhoneNumber, otp string, client model.SessionInfo) (*model.User, string, error) {
+	user, err := s.authenticate(rawPhoneNumber, otp)
+	if err != nil {
+		return nil, "", err
+	}
+
+	session, err := s.createSession(user, client, otpAuthentication)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, session.ID, nil
+}
+
+// authenticate checks a login OTP and returns the number's user, creating
+// it on first login.
+func (s *authService) authenticate(rawPhoneNumber, otp string) (*model.User, error) {
+	number, err := s.phoneParser.Parse(rawPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	phoneNumber := number.E164
+
 	// Verify OTP
-	if storedOTP != otp {
-		return "", fmt.Errorf("invalid OTP")
+	if err := s.checkOTP(model.OTPPurposeLogin, phoneNumber, otp); err != nil {
+		return nil, err
 	}
 
 	// Find or create user
@@ -96,28 +382,196 @@ func (s *authService) VerifyOTP(phoneNumber, otp string) (string, error) {
 		}
 		err = s.userRepo.Create(user)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	}
+	if err := checkAccountStatus(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// StartPhoneChange sends a phone-change OTP to the new number and, when
+// configured, to the user's current number as well. If the second send
+// fails the first OTP is withdrawn, so a retry starts from scratch.
+func (s *authService) StartPhoneChange(userID uint, rawNewPhoneNumber, clientIP, challengeSolution string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
 
-	// Generate JWT token
-	token, err := s.GenerateJWT(user)
+	number, err := s.phoneParser.Parse(rawNewPhoneNumber)
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	if number.E164 == user.PhoneNumber {
+		return ErrSamePhoneNumber
+	}
+	if existing, err := s.userRepo.FindByPhoneNumber(number.E164); err == nil && existing.ID != user.ID {
+		return ErrPhoneNumberTaken
+	}
+
+	err = s.sendOTP(model.OTPPurposePhoneChange, number, func(count int) error {
+		return s.checkChallenge(count, clientIP, challengeSolution)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !s.phoneCfg.ChangeVerifyOldNumber {
+		return nil
+	}
+
+	// The current number already belongs to the user, so it is not gated
+	// behind a second challenge
+	oldNumber, err := s.phoneParser.Parse(user.PhoneNumber)
+	if err == nil {
+		err = s.sendOTP(model.OTPPurposePhoneChange, oldNumber, nil)
+	}
+	if err != nil {
+		// Without the old-number OTP the change cannot be confirmed, so
+		// don't leave a live code on the new number
+		if delErr := s.otpRepo.DeleteOTP(model.OTPPurposePhoneChange, number.E164); delErr != nil {
+			return errors.Join(err, delErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// ConfirmPhoneChange checks the phone-change OTPs, moves the user to the new
+// number and invalidates every token issued before the change. It returns a
+// fresh token pair for the new number.
+func (s *authService) ConfirmPhoneChange(userID uint, rawNewPhoneNumber, otp, oldOTP string, client model.SessionInfo) (*model.TokenPair, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	number, err := s.phoneParser.Parse(rawNewPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	oldPhoneNumber := user.PhoneNumber
+
+	// Each check uses up its code, so a wrong old-number code means
+	// starting over with fresh codes for both numbers
+	if err := s.checkOTP(model.OTPPurposePhoneChange, number.E164, otp); err != nil {
+		return nil, err
 	}
+	if s.phoneCfg.ChangeVerifyOldNumber {
+		if err := s.checkOTP(model.OTPPurposePhoneChange, oldPhoneNumber, oldOTP); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.userRepo.ChangePhoneNumber(user.ID, oldPhoneNumber, number.E164)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ErrPhoneNumberTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.revokeAllTokens(user.ID); err != nil {
+		return nil, err
+	}
+
+	user.PhoneNumber = number.E164
+	return s.startSession(user, client, otpAuthentication)
+}
+
+// checkAccountStatus reports why user may not log in, if anything.
+func checkAccountStatus(user *model.User) error {
+	if user.DeletedAt.Valid {
+		return ErrAccountDeleted
+	}
+	switch user.EffectiveStatus(time.Now().UTC()) {
+	case model.UserStatusSuspended:
+		return ErrAccountSuspended
+	case model.UserStatusBanned:
+		return ErrAccountBanned
+	}
+	return nil
+}
 
-	return token, nil
+// revokeAllTokens invalidates every session, access and refresh token of
+// the user and forgets their trusted devices.
+func (s *authService) revokeAllTokens(userID uint) error {
+	return revokeAllTokens(s.tokenRepo, s.refreshRepo, s.sessionRepo, s.deviceRepo, userID)
+}
+
+func revokeAllTokens(tokenRepo repository.TokenRepository, refreshRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository, deviceRepo repository.TrustedDeviceRepository, userID uint) error {
+	if _, err := tokenRepo.IncrementGeneration(userID); err != nil {
+		return err
+	}
+	if err := refreshRepo.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+	if err := sessionRepo.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+	return deviceRepo.RevokeAllForUser(userID)
+}
+
+// checkOTP uses up the stored code for purpose when otp matches it. Wrong
+// guesses count towards OTP_MAX_ATTEMPTS, after which the code is void.
+func (s *authService) checkOTP(purpose, phoneNumber, otp string) error {
+	if otp == "" {
+		return ErrInvalidOTP
+	}
+	ok, err := s.otpRepo.ConsumeOTP(purpose, phoneNumber, otp, s.otpCfg.MaxAttempts)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidOTP
+	}
+	return nil
 }
 
 func (s *authService) GenerateJWT(user *model.User) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"phone":   user.PhoneNumber,
-		"exp":     time.Now().UTC().Add(time.Hour * 24).Unix(),
+	return s.IssueAccessToken(user, TokenOptions{})
+}
+
+func (s *authService) IssueAccessToken(user *model.User, opts TokenOptions) (string, error) {
+	generation, err := s.tokenRepo.GetGeneration(user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.jwtSecret))
+	claims := token.NewClaims(user.ID, user.PhoneNumber, s.jwtCfg.Issuer, s.jwtCfg.Audience, jti, generation, time.Now().UTC(), s.jwtCfg.TTL)
+	claims.SessionID = opts.SessionID
+	if opts.ClientID != "" {
+		claims.Scope = opts.Scope
+		claims.ClientID = opts.ClientID
+	} else {
+		claims.Roles = user.RoleList()
+		claims.Scope = scopesForRoles(claims.Roles)
+		if s.enricher != nil {
+			// The chain skips failing enrichers, so an error here is not
+			// worth failing the login over either
+			if ext, err := s.enricher.Enrich(context.Background(), user); err == nil {
+				claims.Ext = ext
+			}
+		}
+	}
+	if !opts.AuthTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(opts.AuthTime)
+		claims.AMR = opts.AMR
+		claims.ACR = opts.ACR
+	}
+	if opts.DPoPJKT != "" {
+		claims.Confirmation = &token.Confirmation{JKT: opts.DPoPJKT}
+	}
+	return s.keys.Sign(claims)
 }
 
 func generateOTP(length int) (string, error) {
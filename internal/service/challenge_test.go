@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"otp-auth-service/internal/challenge"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/repository"
+	"testing"
+	"time"
+)
+
+type challengeOTPRepo struct {
+	repository.OTPRepository
+	ipCount int
+}
+
+func (r *challengeOTPRepo) IncrementIPRequestCount(string, time.Duration) (int, error) {
+	r.ipCount++
+	return r.ipCount, nil
+}
+
+type stubVerifier struct{}
+
+func (stubVerifier) Issue() (*challenge.Challenge, error) {
+	return &challenge.Challenge{Type: challenge.TypeProofOfWork}, nil
+}
+
+func (stubVerifier) Verify(string, string) error { return challenge.ErrInvalidSolution }
+
+// The same threshold must trip on the same request for phone and IP.
+func TestCheckChallengeThresholds(t *testing.T) {
+	tests := []struct {
+		name            string
+		phone, ip       int
+		firstChallenged int
+	}{
+		{"phone", 2, 0, 3},
+		{"ip", 0, 2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &authService{
+				otpRepo:   &challengeOTPRepo{},
+				challenge: stubVerifier{},
+				otpCfg: config.OTP{Challenge: config.Challenge{
+					PhoneThreshold: tt.phone,
+					IPThreshold:    tt.ip,
+				}},
+			}
+
+			for request := 1; request <= tt.firstChallenged; request++ {
+				// The phone count excludes the request being made
+				err := s.checkChallenge(request-1, "203.0.113.7", "")
+
+				var challengeErr *ChallengeRequiredError
+				required := errors.As(err, &challengeErr)
+				if want := request >= tt.firstChallenged; required != want {
+					t.Fatalf("request %d: challenge required = %v (%v), want %v", request, required, err, want)
+				}
+			}
+		})
+	}
+}
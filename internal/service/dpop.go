@@ -0,0 +1,130 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/repository"
+	"otp-auth-service/internal/token"
+	"strings"
+	"time"
+)
+
+type DPoPService interface {
+	// VerifyProof checks a DPoP proof sent with a request to method and
+	// requestURL and returns the thumbprint of its key. accessToken is the
+	// token the proof was sent with; it is empty when logging in.
+	VerifyProof(proof, method, requestURL, accessToken string) (string, error)
+	// Nonce returns a fresh nonce for the DPoP-Nonce header.
+	Nonce() (string, error)
+}
+
+type dpopService struct {
+	dpopRepo repository.DPoPRepository
+	dpopCfg  config.DPoP
+	jwtCfg   config.JWT
+}
+
+func NewDPoPService(dpopRepo repository.DPoPRepository, dpopCfg config.DPoP, jwtCfg config.JWT) DPoPService {
+	return &dpopService{
+		dpopRepo: dpopRepo,
+		dpopCfg:  dpopCfg,
+		jwtCfg:   jwtCfg,
+	}
+}
+
+// VerifyProof returns ErrUseDPoPNonce when nonces are required and the
+// proof lacks a current one, and ErrInvalidDPoPProof for any other problem
+// with the proof.
+func (s *dpopService) VerifyProof(proof, method, requestURL, accessToken string) (string, error) {
+	if proof == "" {
+		return "", ErrInvalidDPoPProof
+	}
+
+	parsed, err := token.ParseDPoPProof(proof)
+	if err != nil {
+		return "", ErrInvalidDPoPProof
+	}
+	claims := parsed.Claims
+
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return "", ErrInvalidDPoPProof
+	}
+	if claims.Method != method || !sameURL(claims.URL, requestURL) {
+		return "", ErrInvalidDPoPProof
+	}
+
+	now := time.Now().UTC()
+	issuedAt := claims.IssuedAt.Time
+	if issuedAt.After(now.Add(s.jwtCfg.Leeway)) || issuedAt.Before(now.Add(-s.dpopCfg.ProofLifetime-s.jwtCfg.Leeway)) {
+		return "", ErrInvalidDPoPProof
+	}
+
+	if accessToken != "" && claims.AccessTokenHash != token.AccessTokenHash(accessToken) {
+		return "", ErrInvalidDPoPProof
+	}
+
+	if s.dpopCfg.RequireNonce {
+		if claims.Nonce == "" {
+			return "", ErrUseDPoPNonce
+		}
+		valid, err := s.dpopRepo.NonceExists(claims.Nonce)
+		if err != nil {
+			return "", err
+		}
+		if !valid {
+			return "", ErrUseDPoPNonce
+		}
+	}
+
+	// A proof is good for one request
+	fresh, err := s.dpopRepo.MarkProofUsed(claims.ID, s.dpopCfg.ProofLifetime+2*s.jwtCfg.Leeway)
+	if err != nil {
+		return "", err
+	}
+	if !fresh {
+		return "", ErrInvalidDPoPProof
+	}
+
+	return parsed.Thumbprint, nil
+}
+
+func (s *dpopService) Nonce() (string, error) {
+	nonce, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	if err := s.dpopRepo.StoreNonce(nonce, s.dpopCfg.NonceTTL); err != nil {
+		return "", err
+	}
+	return nonce, nil
+}
+
+// sameURL compares an htu claim with the request URL, ignoring query and
+// fragment and the case of scheme and host (RFC 9449 section 4.3).
+func sameURL(htu, requestURL string) bool {
+	a, err := normalizeHTU(htu)
+	if err != nil {
+		return false
+	}
+	b, err := normalizeHTU(requestURL)
+	if err != nil {
+		return false
+	}
+	return a == b
+}
+
+func normalizeHTU(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", errors.New("htu must be an absolute URL")
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + path, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"otp-auth-service/internal/challenge"
+)
+
+var (
+	ErrRateLimitExceeded = errors.New("rate limit exceeded")
+	ErrPhoneBlocked      = errors.New("phone number is blocked")
+	ErrInvalidPhoneRule  = errors.New("invalid phone rule")
+	ErrCountryNotAllowed = errors.New("country not allowed")
+	ErrDeliveryFailed    = errors.New("OTP delivery failed")
+	ErrInvalidOTP        = errors.New("invalid or expired OTP")
+	ErrSamePhoneNumber   = errors.New("new phone number is the current one")
+	ErrPhoneNumberTaken  = errors.New("phone number is already in use")
+
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrInvalidToken        = errors.New("invalid access token")
+	ErrTokenRevoked        = errors.New("access token has been revoked")
+	ErrUnknownRole         = errors.New("unknown role")
+	ErrInvalidProfile      = errors.New("invalid profile")
+	ErrAccountDeleted      = errors.New("account has been deleted")
+	ErrAccountSuspended    = errors.New("account is suspended")
+	ErrAccountBanned       = errors.New("account is banned")
+	ErrInvalidStatus       = errors.New("invalid status")
+	ErrInvalidDeviceToken  = errors.New("invalid device token")
+	ErrDeviceNotFound      = errors.New("trusted device not found")
+	ErrInvalidDPoPProof    = errors.New("invalid DPoP proof")
+	ErrUseDPoPNonce        = errors.New("DPoP proof must use the server nonce")
+
+	ErrInvalidClient                = errors.New("invalid client credentials")
+	ErrInvalidClientConfig          = errors.New("invalid client configuration")
+	ErrClientNotFound               = errors.New("client not found")
+	ErrAuthorizationRequestNotFound = errors.New("authorization request not found or expired")
+)
+
+// ChallengeRequiredError is returned by RequestOTP when the caller must solve
+// Challenge before an OTP is sent. Failed is set when a solution was
+// submitted but rejected.
+type ChallengeRequiredError struct {
+	Challenge *challenge.Challenge
+	Failed    bool
+}
+
+func (e *ChallengeRequiredError) Error() string {
+	if e.Failed {
+		return "challenge verification failed"
+	}
+	return "challenge required"
+}
+
+// OAuthError is an error reported to OAuth clients with an RFC 6749 error
+// code.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// RedirectError is an authorization error that is reported to the client
+// by redirecting back to its validated redirect URI.
+type RedirectError struct {
+	*OAuthError
+	RedirectURI string
+	State       string
+}
+
+func (e *RedirectError) Unwrap() error {
+	return e.OAuthError
+}
+
+// Location is the redirect URI carrying the error parameters.
+func (e *RedirectError) Location(issuer string) string {
+	params := url.Values{}
+	params.Set("error", e.Code)
+	params.Set("error_description", e.Description)
+	if e.State != "" {
+		params.Set("state", e.State)
+	}
+	params.Set("iss", issuer)
+	return appendQuery(e.RedirectURI, params)
+}
+
+func oauthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
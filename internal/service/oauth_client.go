@@ -0,0 +1,262 @@
+package service
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/url"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/repository"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scopes that OAuth clients may be granted.
+const (
+	ScopeOpenID = "openid"
+	ScopePhone  = "phone"
+)
+
+var (
+	// userScopes are granted by users through the authorization code flow.
+	userScopes = []string{ScopeOpenID, ScopePhone}
+	// serviceScopes are granted to service clients through
+	// client_credentials or API keys.
+	serviceScopes   = []string{ScopeUsersRead}
+	supportedScopes = append(append([]string(nil), userScopes...), serviceScopes...)
+)
+
+// ClientParams describe a client to register.
+type ClientParams struct {
+	Name         string
+	RedirectURIs []string
+	Scopes       []string
+	// GrantTypes defaults to the authorization code grant.
+	GrantTypes []string
+	Public     bool
+	ExpiresAt  *time.Time
+}
+
+type OAuthClientService interface {
+	List() ([]model.OAuthClientResponse, error)
+	Create(params ClientParams) (*model.OAuthClientResponse, error)
+	Find(clientID string) (*model.OAuthClient, error)
+	RotateSecret(clientID string) (*model.OAuthClientResponse, error)
+	Revoke(clientID string) error
+	Authenticate(clientID, clientSecret string) (*model.OAuthClient, error)
+	AuthenticateAPIKey(apiKey string) (*model.OAuthClient, error)
+}
+
+type oauthClientService struct {
+	clientRepo repository.OAuthClientRepository
+	tokenRepo  repository.TokenRepository
+	jwtCfg     config.JWT
+}
+
+func NewOAuthClientService(clientRepo repository.OAuthClientRepository, tokenRepo repository.TokenRepository, jwtCfg config.JWT) OAuthClientService {
+	return &oauthClientService{clientRepo: clientRepo, tokenRepo: tokenRepo, jwtCfg: jwtCfg}
+}
+
+func (s *oauthClientService) List() ([]model.OAuthClientResponse, error) {
+	clients, err := s.clientRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]model.OAuthClientResponse, 0, len(clients))
+	for i := range clients {
+		responses = append(responses, clientResponse(&clients[i]))
+	}
+	return responses, nil
+}
+
+// Create registers a client. The generated secret is returned once and only
+// its hash is stored.
+func (s *oauthClientService) Create(params ClientParams) (*model.OAuthClientResponse, error) {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidClientConfig)
+	}
+
+	grantTypes := params.GrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = []string{model.GrantTypeAuthorizationCode}
+	}
+	for _, grantType := range grantTypes {
+		switch grantType {
+		case model.GrantTypeAuthorizationCode:
+			if len(params.RedirectURIs) == 0 {
+				return nil, fmt.Errorf("%w: the authorization_code grant needs a redirect URI", ErrInvalidClientConfig)
+			}
+		case model.GrantTypeClientCredentials, model.GrantTypeTokenExchange:
+			if params.Public {
+				return nil, fmt.Errorf("%w: public clients cannot use %s", ErrInvalidClientConfig, grantType)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unsupported grant type %q", ErrInvalidClientConfig, grantType)
+		}
+	}
+
+	for _, redirectURI := range params.RedirectURIs {
+		if err := validateRedirectURI(redirectURI); err != nil {
+			return nil, err
+		}
+	}
+	for _, scope := range params.Scopes {
+		if !slices.Contains(supportedScopes, scope) {
+			return nil, fmt.Errorf("%w: unsupported scope %q", ErrInvalidClientConfig, scope)
+		}
+	}
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidClientConfig)
+	}
+
+	clientID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret, secretHash string
+	if !params.Public {
+		secret, err = randomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		secretHash = hashToken(secret)
+	}
+
+	client := &model.OAuthClient{
+		ClientID:     clientID,
+		SecretHash:   secretHash,
+		Name:         name,
+		RedirectURIs: strings.Join(params.RedirectURIs, " "),
+		Scopes:       strings.Join(params.Scopes, " "),
+		GrantTypes:   strings.Join(grantTypes, " "),
+		CreatedAt:    time.Now().UTC(),
+		ExpiresAt:    params.ExpiresAt,
+	}
+	if err := s.clientRepo.Create(client); err != nil {
+		return nil, err
+	}
+
+	response := clientResponse(client)
+	response.ClientSecret = secret
+	return &response, nil
+}
+
+// Find returns an active client by its client ID.
+func (s *oauthClientService) Find(clientID string) (*model.OAuthClient, error) {
+	client, err := s.clientRepo.FindByClientID(clientID)
+	if err != nil {
+		return nil, ErrClientNotFound
+	}
+	return client, nil
+}
+
+// RotateSecret replaces a confidential client's secret. The old secret
+// stops working immediately; tokens already issued stay valid.
+func (s *oauthClientService) RotateSecret(clientID string) (*model.OAuthClientResponse, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.clientRepo.UpdateSecretHash(clientID, hashToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, ErrClientNotFound
+	}
+
+	client, err := s.clientRepo.FindByClientID(clientID)
+	if err != nil {
+		return nil, err
+	}
+	response := clientResponse(client)
+	response.ClientSecret = secret
+	return &response, nil
+}
+
+// Revoke disables the client and every access token issued to it.
+func (s *oauthClientService) Revoke(clientID string) error {
+	revoked, err := s.clientRepo.Revoke(clientID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrClientNotFound
+	}
+	return s.tokenRepo.DenyClient(clientID, s.jwtCfg.TTL+s.jwtCfg.Leeway)
+}
+
+// Authenticate checks client credentials. Public clients authenticate with
+// their client ID alone.
+func (s *oauthClientService) Authenticate(clientID, clientSecret string) (*model.OAuthClient, error) {
+	if clientID == "" {
+		return nil, ErrInvalidClient
+	}
+
+	client, err := s.clientRepo.FindByClientID(clientID)
+	if err != nil {
+		return nil, ErrInvalidClient
+	}
+	if client.Public() {
+		if clientSecret != "" {
+			return nil, ErrInvalidClient
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+		return nil, ErrInvalidClient
+	}
+
+	if err := s.clientRepo.TouchLastUsed(client.ID); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// AuthenticateAPIKey accepts the secret of a client registered for the
+// client_credentials grant as an API key.
+func (s *oauthClientService) AuthenticateAPIKey(apiKey string) (*model.OAuthClient, error) {
+	if apiKey == "" {
+		return nil, ErrInvalidClient
+	}
+
+	client, err := s.clientRepo.FindBySecretHash(hashToken(apiKey))
+	if err != nil || !client.AllowsGrant(model.GrantTypeClientCredentials) {
+		return nil, ErrInvalidClient
+	}
+
+	if err := s.clientRepo.TouchLastUsed(client.ID); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// validateRedirectURI accepts absolute URLs without a fragment, as required
+// for OAuth redirection endpoints.
+func validateRedirectURI(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || !parsed.IsAbs() || parsed.Host == "" || parsed.Fragment != "" {
+		return fmt.Errorf("%w: invalid redirect URI %q", ErrInvalidClientConfig, raw)
+	}
+	return nil
+}
+
+func clientResponse(client *model.OAuthClient) model.OAuthClientResponse {
+	return model.OAuthClientResponse{
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIList(),
+		Scopes:       client.ScopeList(),
+		GrantTypes:   client.GrantTypeList(),
+		Public:       client.Public(),
+		CreatedAt:    client.CreatedAt,
+		ExpiresAt:    client.ExpiresAt,
+		LastUsedAt:   client.LastUsedAt,
+		RevokedAt:    client.RevokedAt,
+	}
+}
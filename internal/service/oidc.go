@@ -0,0 +1,478 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/repository"
+	"otp-auth-service/internal/token"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// RFC 6749 error codes.
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthUnauthorizedClient      = "unauthorized_client"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthInvalidScope            = "invalid_scope"
+	// RFC 8693
+	OAuthInvalidTarget = "invalid_target"
+)
+
+const codeChallengeMethodS256 = "S256"
+
+// AuthorizeParams are the query parameters of an authorization request.
+type AuthorizeParams struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// TokenParams are the form parameters of a token request.
+type TokenParams struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	Scope        string
+	ClientID     string
+	ClientSecret string
+	// Token exchange (RFC 8693)
+	SubjectToken       string
+	SubjectTokenType   string
+	RequestedTokenType string
+	Audience           string
+}
+
+type OIDCTokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+	IDToken         string `json:"id_token,omitempty"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"`
+	Scope           string `json:"scope"`
+}
+
+type DiscoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	AuthorizationResponseIssParameter bool     `json:"authorization_response_iss_parameter_supported"`
+}
+
+type OIDCService interface {
+	Discovery() *DiscoveryDocument
+	Issuer() string
+	Authorize(params AuthorizeParams) (*model.AuthorizationRequest, error)
+	CompleteAuthorization(requestID, phoneNumber, otp string, client model.SessionInfo) (string, error)
+	Token(params TokenParams) (*OIDCTokenResponse, error)
+	UserInfo(userID uint, scope string) (map[string]interface{}, error)
+}
+
+type oidcService struct {
+	authService   AuthService
+	userService   UserService
+	clientService OAuthClientService
+	validator     TokenValidator
+	authzRepo     repository.AuthorizationRepository
+	userRepo      repository.UserRepository
+	oidcCfg       config.OIDC
+	jwtCfg        config.JWT
+	exchangeCfg   config.TokenExchange
+	keys          *token.KeySet
+}
+
+func NewOIDCService(authService AuthService, userService UserService, clientService OAuthClientService, validator TokenValidator, authzRepo repository.AuthorizationRepository, userRepo repository.UserRepository, oidcCfg config.OIDC, jwtCfg config.JWT, exchangeCfg config.TokenExchange, keys *token.KeySet) OIDCService {
+	return &oidcService{
+		authService:   authService,
+		userService:   userService,
+		clientService: clientService,
+		validator:     validator,
+		authzRepo:     authzRepo,
+		userRepo:      userRepo,
+		oidcCfg:       oidcCfg,
+		jwtCfg:        jwtCfg,
+		exchangeCfg:   exchangeCfg,
+		keys:          keys,
+	}
+}
+
+func (s *oidcService) Issuer() string {
+	return strings.TrimSuffix(s.jwtCfg.Issuer, "/")
+}
+
+func (s *oidcService) Discovery() *DiscoveryDocument {
+	issuer := s.Issuer()
+	grantTypes := []string{model.GrantTypeAuthorizationCode, model.GrantTypeClientCredentials}
+	if len(s.exchangeCfg.Audiences) > 0 {
+		grantTypes = append(grantTypes, model.GrantTypeTokenExchange)
+	}
+	return &DiscoveryDocument{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserinfoEndpoint:                  issuer + "/oauth/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               grantTypes,
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  s.keys.ValidMethods(),
+		ScopesSupported:                   supportedScopes,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{codeChallengeMethodS256},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "amr", "acr", "nonce", "sid", "phone_number", "phone_number_verified"},
+		AuthorizationResponseIssParameter: true,
+	}
+}
+
+// Authorize validates an authorization request and stores it until the user
+// has logged in. Errors found before the redirect URI is trusted are plain
+// *OAuthError values; later ones are *RedirectError.
+func (s *oidcService) Authorize(params AuthorizeParams) (*model.AuthorizationRequest, error) {
+	client, err := s.clientService.Find(params.ClientID)
+	if err != nil {
+		return nil, oauthError(OAuthInvalidClient, "unknown client")
+	}
+	if params.RedirectURI == "" || !slices.Contains(client.RedirectURIList(), params.RedirectURI) {
+		return nil, oauthError(OAuthInvalidRequest, "redirect_uri is not registered for this client")
+	}
+
+	redirectErr := func(code, description string) error {
+		return &RedirectError{
+			OAuthError:  oauthError(code, description),
+			RedirectURI: params.RedirectURI,
+			State:       params.State,
+		}
+	}
+
+	if params.ResponseType != "code" {
+		return nil, redirectErr(OAuthUnsupportedResponseType, "only the code response type is supported")
+	}
+	if !client.AllowsGrant(model.GrantTypeAuthorizationCode) {
+		return nil, redirectErr(OAuthUnauthorizedClient, "client is not registered for the authorization code grant")
+	}
+
+	scopes := strings.Fields(params.Scope)
+	if !slices.Contains(scopes, ScopeOpenID) {
+		return nil, redirectErr(OAuthInvalidScope, "the openid scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(userScopes, scope) || !slices.Contains(client.ScopeList(), scope) {
+			return nil, redirectErr(OAuthInvalidScope, "scope "+scope+" is not allowed for this client")
+		}
+	}
+
+	// PKCE is required for every client
+	if params.CodeChallengeMethod != codeChallengeMethodS256 || !validCodeChallenge(params.CodeChallenge) {
+		return nil, redirectErr(OAuthInvalidRequest, "a S256 code_challenge is required")
+	}
+
+	id, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &model.AuthorizationRequest{
+		ID:                  id,
+		ClientID:            client.ClientID,
+		RedirectURI:         params.RedirectURI,
+		Scope:               strings.Join(scopes, " "),
+		State:               params.State,
+		Nonce:               params.Nonce,
+		CodeChallenge:       params.CodeChallenge,
+		CodeChallengeMethod: params.CodeChallengeMethod,
+		ExpiresAt:           time.Now().UTC().Add(s.oidcCfg.RequestTTL),
+	}
+	if err := s.authzRepo.StoreRequest(request, s.oidcCfg.RequestTTL); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// CompleteAuthorization logs the user in with an OTP requested through
+// /auth/request-otp and returns the client redirect URI carrying the
+// authorization code.
+func (s *oidcService) CompleteAuthorization(requestID, phoneNumber, otp string, client model.SessionInfo) (string, error) {
+	request, err := s.authzRepo.GetRequest(requestID)
+	if err != nil {
+		return "", ErrAuthorizationRequestNotFound
+	}
+
+	if client.DeviceName == "" {
+		if registered, err := s.clientService.Find(request.ClientID); err == nil {
+			client.DeviceName = registered.Name
+		}
+	}
+
+	user, sessionID, err := s.authService.Login(phoneNumber, otp, client)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.authzRepo.StoreCode(hashToken(code), &model.AuthorizationCode{
+		ClientID:            request.ClientID,
+		UserID:              user.ID,
+		SessionID:           sessionID,
+		RedirectURI:         request.RedirectURI,
+		Scope:               request.Scope,
+		Nonce:               request.Nonce,
+		CodeChallenge:       request.CodeChallenge,
+		CodeChallengeMethod: request.CodeChallengeMethod,
+		AuthTime:            time.Now().UTC(),
+	}, s.oidcCfg.CodeTTL)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.authzRepo.DeleteRequest(requestID); err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("code", code)
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+	params.Set("iss", s.Issuer())
+	return appendQuery(request.RedirectURI, params), nil
+}
+
+// Token serves the token endpoint. The authorization code grant is only
+// available while the OIDC provider is enabled, token exchange only while it
+// has audiences configured.
+func (s *oidcService) Token(params TokenParams) (*OIDCTokenResponse, error) {
+	switch params.GrantType {
+	case model.GrantTypeAuthorizationCode:
+		if !s.oidcCfg.Enabled {
+			return nil, oauthError(OAuthUnsupportedGrantType, "the authorization_code grant is disabled")
+		}
+	case model.GrantTypeClientCredentials:
+	case model.GrantTypeTokenExchange:
+		if len(s.exchangeCfg.Audiences) == 0 {
+			return nil, oauthError(OAuthUnsupportedGrantType, "token exchange is disabled")
+		}
+	default:
+		return nil, oauthError(OAuthUnsupportedGrantType, "unsupported grant type")
+	}
+
+	client, err := s.clientService.Authenticate(params.ClientID, params.ClientSecret)
+	if err != nil {
+		return nil, oauthError(OAuthInvalidClient, "client authentication failed")
+	}
+	if !client.AllowsGrant(params.GrantType) {
+		return nil, oauthError(OAuthUnauthorizedClient, "client is not registered for this grant type")
+	}
+
+	switch params.GrantType {
+	case model.GrantTypeClientCredentials:
+		return s.clientCredentials(client, params.Scope)
+	case model.GrantTypeTokenExchange:
+		return s.exchangeToken(client, params)
+	}
+	return s.exchangeCode(client, params)
+}
+
+// clientCredentials issues a service token carrying the requested scopes,
+// or every service scope of the client when none are requested.
+func (s *oidcService) clientCredentials(client *model.OAuthClient, requestedScope string) (*OIDCTokenResponse, error) {
+	scope, ok := grantedServiceScopes(client, requestedScope)
+	if !ok {
+		return nil, oauthError(OAuthInvalidScope, "requested scope is not allowed for this client")
+	}
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := token.NewServiceClaims(client.ClientID, scope, s.jwtCfg.Issuer, s.jwtCfg.Audience, jti, time.Now().UTC(), s.jwtCfg.TTL)
+	accessToken, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.jwtCfg.TTL.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// exchangeCode redeems an authorization code for an access token and an ID
+// token.
+func (s *oidcService) exchangeCode(client *model.OAuthClient, params TokenParams) (*OIDCTokenResponse, error) {
+	if params.Code == "" {
+		return nil, oauthError(OAuthInvalidRequest, "code is required")
+	}
+	code, err := s.authzRepo.ConsumeCode(hashToken(params.Code))
+	if err != nil {
+		return nil, oauthError(OAuthInvalidGrant, "invalid or expired code")
+	}
+	if code.ClientID != client.ClientID {
+		return nil, oauthError(OAuthInvalidGrant, "code was issued to another client")
+	}
+	if code.RedirectURI != params.RedirectURI {
+		return nil, oauthError(OAuthInvalidGrant, "redirect_uri does not match the authorization request")
+	}
+	if !verifyCodeChallenge(code.CodeChallenge, params.CodeVerifier) {
+		return nil, oauthError(OAuthInvalidGrant, "code_verifier does not match the code challenge")
+	}
+
+	user, err := s.userRepo.FindByID(code.UserID)
+	if err != nil {
+		return nil, oauthError(OAuthInvalidGrant, "user no longer exists")
+	}
+	if err := checkAccountStatus(user); err != nil {
+		return nil, oauthError(OAuthInvalidGrant, err.Error())
+	}
+
+	accessToken, err := s.authService.IssueAccessToken(user, TokenOptions{
+		SessionID: code.SessionID,
+		Scope:     code.Scope,
+		ClientID:  client.ClientID,
+		AuthTime:  code.AuthTime,
+		AMR:       otpAuthentication.methods,
+		ACR:       otpAuthentication.acr,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := s.signIDToken(user, client.ClientID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCTokenResponse{
+		AccessToken: accessToken,
+		IDToken:     idToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.jwtCfg.TTL.Seconds()),
+		Scope:       code.Scope,
+	}, nil
+}
+
+// UserInfo returns the claims about the user allowed by scope. Tokens
+// without a scope come from the first-party login and see every claim.
+func (s *oidcService) UserInfo(userID uint, scope string) (map[string]interface{}, error) {
+	user, err := s.userService.GetMe(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	info := map[string]interface{}{
+		"sub": strconv.FormatUint(uint64(user.ID), 10),
+	}
+	if scope == "" || slices.Contains(strings.Fields(scope), ScopePhone) {
+		info["phone_number"] = user.PhoneNumber
+		info["phone_number_verified"] = true
+	}
+	return info, nil
+}
+
+func (s *oidcService) signIDToken(user *model.User, clientID string, code *model.AuthorizationCode) (string, error) {
+	now := time.Now().UTC()
+	claims := &token.IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Issuer:    s.jwtCfg.Issuer,
+			Audience:  jwt.ClaimStrings{clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.jwtCfg.TTL)),
+		},
+		Nonce:     code.Nonce,
+		AuthTime:  code.AuthTime.Unix(),
+		AMR:       otpAuthentication.methods,
+		ACR:       otpAuthentication.acr,
+		SessionID: code.SessionID,
+	}
+	if slices.Contains(strings.Fields(code.Scope), ScopePhone) {
+		claims.PhoneNumber = user.PhoneNumber
+		claims.PhoneNumberVerified = true
+	}
+	return s.keys.Sign(claims)
+}
+
+// grantedServiceScopes intersects the client's scopes with the service
+// scopes. ok is false when requested names a scope outside that set.
+func grantedServiceScopes(client *model.OAuthClient, requested string) (string, bool) {
+	var allowed []string
+	for _, scope := range client.ScopeList() {
+		if slices.Contains(serviceScopes, scope) {
+			allowed = append(allowed, scope)
+		}
+	}
+
+	if requested == "" {
+		return strings.Join(allowed, " "), true
+	}
+	for _, scope := range strings.Fields(requested) {
+		if !slices.Contains(allowed, scope) {
+			return "", false
+		}
+	}
+	return strings.Join(strings.Fields(requested), " "), true
+}
+
+// validCodeChallenge accepts a base64url SHA-256 digest as produced for the
+// S256 method.
+func validCodeChallenge(challenge string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(challenge)
+	return err == nil && len(decoded) == sha256.Size
+}
+
+func verifyCodeChallenge(challenge, verifier string) bool {
+	// RFC 7636 verifiers are 43 to 128 characters long
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// appendQuery adds params to rawURL, keeping any query it already has.
+func appendQuery(rawURL string, params url.Values) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := parsed.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/phone"
+	"otp-auth-service/internal/repository"
+	"time"
+)
+
+const auditActionOTPReset = "otp.reset"
+
+type OTPAdminService interface {
+	GetPhoneState(number *phone.Number) (*model.PhoneOTPState, error)
+	ResetPhoneState(number *phone.Number, actor, reason string, clearOTP bool) (*model.PhoneOTPState, error)
+}
+
+type otpAdminService struct {
+	otpRepo       repository.OTPRepository
+	phoneRuleRepo repository.PhoneRuleRepository
+	auditRepo     repository.AuditRepository
+	otpCfg        config.OTP
+}
+
+func NewOTPAdminService(otpRepo repository.OTPRepository, phoneRuleRepo repository.PhoneRuleRepository, auditRepo repository.AuditRepository, otpCfg config.OTP) OTPAdminService {
+	return &otpAdminService{
+		otpRepo:       otpRepo,
+		phoneRuleRepo: phoneRuleRepo,
+		auditRepo:     auditRepo,
+		otpCfg:        otpCfg,
+	}
+}
+
+// GetPhoneState mirrors the checks made by RequestOTP so support sees the
+// same numbers the limiter acts on, along with the active OTP and wrong
+// guesses of every purpose.
+func (s *otpAdminService) GetPhoneState(number *phone.Number) (*model.PhoneOTPState, error) {
+	phoneNumber := number.E164
+	policy := resolvePolicy(s.otpCfg, number.Region)
+
+	state := &model.PhoneOTPState{
+		PhoneNumber:   phoneNumber,
+		Country:       number.Region,
+		RateLimit:     policy.rateLimit,
+		WindowSeconds: int(policy.rateWindow.Seconds()),
+	}
+
+	rules, err := s.phoneRuleRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	if rule := matchPhoneRule(rules, phoneNumber); rule != nil {
+		state.List = rule.List
+	}
+
+	since := time.Now().UTC().Add(-policy.rateWindow)
+	resetAt, err := s.otpRepo.GetRequestCountResetAt(phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if resetAt.After(since) {
+		since = resetAt
+		state.CountersResetAt = &resetAt
+	}
+
+	times, err := s.otpRepo.GetRequestTimes(phoneNumber, since)
+	if err != nil {
+		return nil, err
+	}
+	state.RequestsInWindow = len(times)
+
+	state.SuccessfulInWindow, err = s.otpRepo.GetSuccessfulRequestCount(phoneNumber, since)
+	if err != nil {
+		return nil, err
+	}
+
+	// RequestOTP rejects once more than RateLimit requests are in the window
+	exempt := state.List == model.PhoneListAllow || state.List == model.PhoneListTest
+	if !exempt {
+		state.RemainingQuota = max(policy.rateLimit+1-state.RequestsInWindow, 0)
+		if state.RequestsInWindow > policy.rateLimit {
+			state.LockedOut = true
+			until := times[state.RequestsInWindow-policy.rateLimit-1].Add(policy.rateWindow)
+			state.LockedUntil = &until
+		}
+
+		threshold := s.otpCfg.Challenge.PhoneThreshold
+		provider := s.otpCfg.Challenge.Provider
+		state.ChallengeRequired = provider != "" && provider != "none" && threshold > 0 && state.RequestsInWindow >= threshold
+	} else {
+		state.RemainingQuota = policy.rateLimit + 1
+	}
+
+	state.MaxAttempts = s.otpCfg.MaxAttempts
+	state.OTPs = make([]model.PurposeOTPState, 0, len(model.OTPPurposes))
+	for _, purpose := range model.OTPPurposes {
+		ttl, err := s.otpRepo.GetOTPTTL(purpose, phoneNumber)
+		if err != nil {
+			return nil, err
+		}
+		attempts, err := s.otpRepo.GetOTPAttempts(purpose, phoneNumber)
+		if err != nil {
+			return nil, err
+		}
+		otp := model.PurposeOTPState{
+			Purpose:        purpose,
+			Active:         ttl > 0,
+			TTLSeconds:     int(ttl.Seconds()),
+			FailedAttempts: attempts,
+		}
+		if otp.Active {
+			otp.AttemptsLeft = max(s.otpCfg.MaxAttempts-attempts, 0)
+		}
+		state.OTPs = append(state.OTPs, otp)
+
+		if purpose == model.OTPPurposeLogin {
+			state.OTPActive = otp.Active
+			state.OTPTTLSeconds = otp.TTLSeconds
+		}
+	}
+
+	return state, nil
+}
+
+func (s *otpAdminService) ResetPhoneState(number *phone.Number, actor, reason string, clearOTP bool) (*model.PhoneOTPState, error) {
+	phoneNumber := number.E164
+	policy := resolvePolicy(s.otpCfg, number.Region)
+
+	if err := s.otpRepo.ResetRequestCount(phoneNumber, policy.rateWindow); err != nil {
+		return nil, err
+	}
+
+	// Wrong guesses are forgiven for every purpose; clearOTP also withdraws
+	// the codes themselves
+	for _, purpose := range model.OTPPurposes {
+		reset := s.otpRepo.ResetOTPAttempts
+		if clearOTP {
+			reset = s.otpRepo.DeleteOTP
+		}
+		if err := reset(purpose, phoneNumber); err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.auditRepo.Record(&model.AuditLog{
+		Actor:     actor,
+		Action:    auditActionOTPReset,
+		Target:    phoneNumber,
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetPhoneState(number)
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"otp-auth-service/internal/config"
+	"time"
+)
+
+// otpPolicy is the effective OTP configuration for one country.
+type otpPolicy struct {
+	length     int
+	expiry     time.Duration
+	channels   []string
+	rateLimit  int
+	rateWindow time.Duration
+}
+
+// resolvePolicy applies the overrides configured for region on top of the
+// global OTP settings. Zero values in an override inherit the global value.
+func resolvePolicy(cfg config.OTP, region string) otpPolicy {
+	policy := otpPolicy{
+		length:     cfg.Length,
+		expiry:     cfg.Expiry,
+		channels:   cfg.Channels,
+		rateLimit:  cfg.RateLimit,
+		rateWindow: cfg.RateWindow,
+	}
+
+	override, ok := cfg.CountryPolicies[region]
+	if !ok {
+		return policy
+	}
+
+	if override.Length > 0 {
+		policy.length = override.Length
+	}
+	if override.Expiry > 0 {
+		policy.expiry = override.Expiry
+	}
+	if len(override.Channels) > 0 {
+		policy.channels = override.Channels
+	}
+	if override.RateLimit > 0 {
+		policy.rateLimit = override.RateLimit
+	}
+	if override.RateWindow > 0 {
+		policy.rateWindow = override.RateWindow
+	}
+	return policy
+}
+
+// countryAllowed reports whether OTPs may be sent to numbers in region.
+func countryAllowed(cfg config.OTP, region string) bool {
+	for _, denied := range cfg.DeniedCountries {
+		if denied == region {
+			return false
+		}
+	}
+
+	if len(cfg.AllowedCountries) == 0 {
+		return true
+	}
+	for _, allowed := range cfg.AllowedCountries {
+		if allowed == region {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"fmt"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/phone"
+	"otp-auth-service/internal/repository"
+)
+
+type PhoneRuleService interface {
+	List() ([]model.PhoneRule, error)
+	Create(list, matchType, value, note string) (*model.PhoneRule, error)
+	Delete(id uint) error
+}
+
+type phoneRuleService struct {
+	phoneRuleRepo repository.PhoneRuleRepository
+	phoneParser   *phone.Parser
+}
+
+func NewPhoneRuleService(phoneRuleRepo repository.PhoneRuleRepository, phoneParser *phone.Parser) PhoneRuleService {
+	return &phoneRuleService{phoneRuleRepo: phoneRuleRepo, phoneParser: phoneParser}
+}
+
+func (s *phoneRuleService) List() ([]model.PhoneRule, error) {
+	return s.phoneRuleRepo.List()
+}
+
+func (s *phoneRuleService) Create(list, matchType, value, note string) (*model.PhoneRule, error) {
+	switch list {
+	case model.PhoneListAllow, model.PhoneListDeny, model.PhoneListTest:
+	default:
+		return nil, fmt.Errorf("%w: unknown list %q", ErrInvalidPhoneRule, list)
+	}
+
+	// Rules are matched against E.164 numbers, so store values in that form
+	switch matchType {
+	case model.PhoneMatchExact:
+		number, err := s.phoneParser.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneRule, err)
+		}
+		value = number.E164
+	case model.PhoneMatchPrefix:
+		value = phone.NormalizePrefix(value)
+		if value == "+" {
+			return nil, fmt.Errorf("%w: invalid prefix", ErrInvalidPhoneRule)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown match type %q", ErrInvalidPhoneRule, matchType)
+	}
+
+	rule := &model.PhoneRule{
+		List:      list,
+		MatchType: matchType,
+		Value:     value,
+		Note:      note,
+	}
+	if err := s.phoneRuleRepo.Create(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *phoneRuleService) Delete(id uint) error {
+	return s.phoneRuleRepo.Delete(id)
+}
+
+// matchPhoneRule picks the rule that applies to phoneNumber. Deny rules win
+// over test rules, which win over allow rules, so a blocked prefix cannot be
+// bypassed by a broader allow entry.
+func matchPhoneRule(rules []model.PhoneRule, phoneNumber string) *model.PhoneRule {
+	for _, list := range []string{model.PhoneListDeny, model.PhoneListTest, model.PhoneListAllow} {
+		for i := range rules {
+			if rules[i].List == list && rules[i].Matches(phoneNumber) {
+				return &rules[i]
+			}
+		}
+	}
+	return nil
+}
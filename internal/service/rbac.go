@@ -0,0 +1,36 @@
+package service
+
+import (
+	"otp-auth-service/internal/model"
+	"slices"
+	"strings"
+)
+
+// Scopes granted to first-party tokens by the user's roles.
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+)
+
+var roleScopes = map[string][]string{
+	model.RoleAdmin: {ScopeUsersRead, ScopeUsersWrite},
+}
+
+// validRole reports whether role can be assigned to users.
+func validRole(role string) bool {
+	_, ok := roleScopes[role]
+	return ok
+}
+
+// scopesForRoles returns the space separated scopes granted by roles.
+func scopesForRoles(roles []string) string {
+	var scopes []string
+	for _, role := range roles {
+		for _, scope := range roleScopes[role] {
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return strings.Join(scopes, " ")
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"otp-auth-service/internal/model"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// startSession records a new session for user, authenticated by auth, and
+// issues its first token pair.
+func (s *authService) startSession(user *model.User, client model.SessionInfo, auth authentication) (*model.TokenPair, error) {
+	session, err := s.createSession(user, client, auth)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user, session)
+}
+
+func (s *authService) createSession(user *model.User, client model.SessionInfo, auth authentication) (*model.Session, error) {
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	session := &model.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		DeviceName: truncate(client.DeviceName, maxDeviceNameLength),
+		IPAddress:  client.IPAddress,
+		UserAgent:  truncate(client.UserAgent, maxUserAgentLength),
+		DPoPJKT:    client.DPoPJKT,
+		AuthTime:   now,
+		AMR:        strings.Join(auth.methods, " "),
+		ACR:        auth.acr,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// issueTokens signs an access token for the session and creates a refresh
+// token. The session ID doubles as the refresh token family.
+func (s *authService) issueTokens(user *model.User, session *model.Session) (*model.TokenPair, error) {
+	accessToken, err := s.IssueAccessToken(user, sessionTokenOptions(session))
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	err = s.refreshRepo.Create(&model.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  session.ID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: now.Add(s.jwtCfg.RefreshTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    tokenType(session.DPoPJKT),
+		ExpiresIn:    int(s.jwtCfg.TTL.Seconds()),
+	}, nil
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already
+// rotated means it leaked, so the whole session is revoked. Sessions bound
+// to a DPoP key only refresh with a proof from that key.
+func (s *authService) Refresh(refreshToken string, client model.SessionInfo) (*model.TokenPair, error) {
+	stored, err := s.refreshRepo.FindByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if stored.RevokedAt != nil || time.Now().UTC().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Checked before rotation so a stolen token is useless without the key
+	session, err := s.sessionRepo.FindByID(stored.FamilyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Refresh tokens issued before sessions were recorded
+		session, err = &model.Session{ID: stored.FamilyID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.DPoPJKT != "" && client.DPoPJKT != session.DPoPJKT {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if stored.UsedAt != nil {
+		if err := s.revokeSession(stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	rotated, err := s.refreshRepo.MarkUsed(stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Lost a race with another refresh of the same token
+		if err := s.revokeSession(stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	user, err := s.userRepo.FindByID(stored.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err := checkAccountStatus(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.sessionRepo.Touch(stored.FamilyID, client.IPAddress, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, session)
+}
+
+// Logout denies the access token identified by jti until it expires and
+// ends its session. A refresh token, when given, has its session revoked
+// too, which covers tokens issued without a sid.
+func (s *authService) Logout(userID uint, sessionID, jti string, expiresAt time.Time, refreshToken string) error {
+	if jti != "" {
+		if err := s.tokenRepo.DenyJTI(jti, time.Until(expiresAt)); err != nil {
+			return err
+		}
+	}
+	if sessionID != "" {
+		if err := s.revokeSession(sessionID); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	stored, err := s.refreshRepo.FindByHash(hashToken(refreshToken))
+	if err != nil || stored.UserID != userID {
+		return nil
+	}
+	return s.revokeSession(stored.FamilyID)
+}
+
+func (s *authService) revokeSession(sessionID string) error {
+	return revokeSession(s.sessionRepo, s.tokenRepo, s.refreshRepo, s.jwtCfg, sessionID)
+}
+
+// tokenType is the token_type of access tokens bound to dpopJKT.
+func tokenType(dpopJKT string) string {
+	if dpopJKT != "" {
+		return "DPoP"
+	}
+	return "Bearer"
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncate cuts s to at most max bytes without splitting a UTF-8 sequence.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"errors"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Limits matching the sessions table columns.
+const (
+	maxDeviceNameLength = 100
+	maxUserAgentLength  = 512
+)
+
+type SessionService interface {
+	ListSessions(userID uint, currentSessionID string) ([]model.SessionResponse, error)
+	RevokeSession(userID uint, sessionID string) error
+	RevokeOtherSessions(userID uint, currentSessionID string) (int, error)
+}
+
+type sessionService struct {
+	sessionRepo repository.SessionRepository
+	tokenRepo   repository.TokenRepository
+	refreshRepo repository.RefreshTokenRepository
+	jwtCfg      config.JWT
+}
+
+func NewSessionService(sessionRepo repository.SessionRepository, tokenRepo repository.TokenRepository, refreshRepo repository.RefreshTokenRepository, jwtCfg config.JWT) SessionService {
+	return &sessionService{
+		sessionRepo: sessionRepo,
+		tokenRepo:   tokenRepo,
+		refreshRepo: refreshRepo,
+		jwtCfg:      jwtCfg,
+	}
+}
+
+// ListSessions returns the user's sessions that can still be refreshed.
+func (s *sessionService) ListSessions(userID uint, currentSessionID string) ([]model.SessionResponse, error) {
+	sessions, err := s.sessionRepo.ListActive(userID, time.Now().UTC().Add(-s.jwtCfg.RefreshTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]model.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, model.SessionResponse{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return responses, nil
+}
+
+func (s *sessionService) RevokeSession(userID uint, sessionID string) error {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && session.UserID != userID) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return revokeSession(s.sessionRepo, s.tokenRepo, s.refreshRepo, s.jwtCfg, sessionID)
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current
+// session and reports how many sessions were revoked.
+func (s *sessionService) RevokeOtherSessions(userID uint, currentSessionID string) (int, error) {
+	sessions, err := s.sessionRepo.ListActive(userID, time.Now().UTC().Add(-s.jwtCfg.RefreshTTL))
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID == currentSessionID {
+			continue
+		}
+		if err := revokeSession(s.sessionRepo, s.tokenRepo, s.refreshRepo, s.jwtCfg, session.ID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// revokeSession ends a session: its refresh tokens stop working and its
+// access tokens are denied until they would have expired.
+func revokeSession(sessionRepo repository.SessionRepository, tokenRepo repository.TokenRepository, refreshRepo repository.RefreshTokenRepository, jwtCfg config.JWT, sessionID string) error {
+	if err := tokenRepo.DenySession(sessionID, jwtCfg.TTL+jwtCfg.Leeway); err != nil {
+		return err
+	}
+	if err := refreshRepo.RevokeFamily(sessionID); err != nil {
+		return err
+	}
+	return sessionRepo.Revoke(sessionID)
+}
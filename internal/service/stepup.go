@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"otp-auth-service/internal/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Authentication methods recorded in the amr claim (RFC 8176).
+const (
+	AMROTP = "otp"
+	// AMRDevice is proof of possession of a software-secured key, here the
+	// token of a trusted device.
+	AMRDevice = "swk"
+)
+
+// Assurance levels recorded in the acr claim, weakest first.
+const (
+	ACRDevice = "1"
+	ACROTP    = "2"
+)
+
+var acrLevels = map[string]int{
+	ACRDevice: 1,
+	ACROTP:    2,
+}
+
+// ACRSatisfies reports whether acr is at least the assurance level min.
+// An empty min is satisfied by any token.
+func ACRSatisfies(acr, min string) bool {
+	if min == "" {
+		return true
+	}
+	level, ok := acrLevels[acr]
+	return ok && level >= acrLevels[min]
+}
+
+// authentication describes how a user proved who they are.
+type authentication struct {
+	methods []string
+	acr     string
+}
+
+var (
+	otpAuthentication    = authentication{methods: []string{AMROTP}, acr: ACROTP}
+	deviceAuthentication = authentication{methods: []string{AMRDevice}, acr: ACRDevice}
+)
+
+// StartReauthentication sends a re-authentication OTP to the user's
+// current number. Like the old-number OTP of a phone change it is rate
+// limited but never gated behind a challenge.
+func (s *authService) StartReauthentication(userID uint) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	number, err := s.phoneParser.Parse(user.PhoneNumber)
+	if err != nil {
+		return err
+	}
+	return s.sendOTP(model.OTPPurposeReauth, number, nil)
+}
+
+// Reauthenticate checks a re-authentication OTP, records the fresh
+// authentication in the session and returns an access token carrying it.
+// The session's refresh token stays valid and later refreshes keep the new
+// auth_time.
+func (s *authService) Reauthenticate(userID uint, sessionID, otp string) (*model.TokenPair, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && (session.UserID != userID || session.RevokedAt != nil)) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkOTP(model.OTPPurposeReauth, user.PhoneNumber, otp); err != nil {
+		return nil, err
+	}
+
+	session.AuthTime = time.Now().UTC()
+	session.AMR = strings.Join(otpAuthentication.methods, " ")
+	session.ACR = otpAuthentication.acr
+	err = s.sessionRepo.UpdateAuthentication(session.ID, session.AuthTime, session.AMR, session.ACR)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.IssueAccessToken(user, sessionTokenOptions(session))
+	if err != nil {
+		return nil, err
+	}
+	return &model.TokenPair{
+		AccessToken: accessToken,
+		TokenType:   tokenType(session.DPoPJKT),
+		ExpiresIn:   int(s.jwtCfg.TTL.Seconds()),
+	}, nil
+}
+
+// sessionTokenOptions carries the session's key binding and
+// authentication into its access tokens.
+func sessionTokenOptions(session *model.Session) TokenOptions {
+	return TokenOptions{
+		SessionID: session.ID,
+		DPoPJKT:   session.DPoPJKT,
+		AuthTime:  session.AuthTime,
+		AMR:       session.AMRList(),
+		ACR:       session.ACR,
+	}
+}
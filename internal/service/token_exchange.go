@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/token"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TokenTypeAccessToken is the RFC 8693 type of the tokens token exchange
+// accepts and issues.
+const TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+// exchangeToken trades a user's access token for a short-lived token
+// addressed to another service. The new token never carries more scopes
+// than the subject token and names the client in its act claim.
+func (s *oidcService) exchangeToken(client *model.OAuthClient, params TokenParams) (*OIDCTokenResponse, error) {
+	if params.SubjectToken == "" {
+		return nil, oauthError(OAuthInvalidRequest, "subject_token is required")
+	}
+	if params.SubjectTokenType != TokenTypeAccessToken {
+		return nil, oauthError(OAuthInvalidRequest, "subject_token_type must be "+TokenTypeAccessToken)
+	}
+	if params.RequestedTokenType != "" && params.RequestedTokenType != TokenTypeAccessToken {
+		return nil, oauthError(OAuthInvalidRequest, "only access tokens can be requested")
+	}
+	if params.Audience == "" || !slices.Contains(s.exchangeCfg.Audiences, params.Audience) {
+		return nil, oauthError(OAuthInvalidTarget, "audience is not allowed for token exchange")
+	}
+
+	subject, err := s.validator.ValidateAccessToken(params.SubjectToken)
+	if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenRevoked) {
+		return nil, oauthError(OAuthInvalidGrant, "invalid subject token")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subject.IsService() {
+		return nil, oauthError(OAuthInvalidGrant, "only user tokens can be exchanged")
+	}
+	// A sender-constrained token is only usable by its holder
+	if subject.Confirmation != nil {
+		return nil, oauthError(OAuthInvalidGrant, "DPoP-bound tokens cannot be exchanged")
+	}
+
+	scope, ok := downscope(subject.Scope, params.Scope)
+	if !ok {
+		return nil, oauthError(OAuthInvalidScope, "requested scope exceeds the subject token's scope")
+	}
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.exchangeCfg.TTL)
+	if subjectExpiry := subject.ExpiresAt.Time; subjectExpiry.Before(expiresAt) {
+		expiresAt = subjectExpiry
+	}
+
+	claims := &token.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject.Subject,
+			Issuer:    s.jwtCfg.Issuer,
+			Audience:  jwt.ClaimStrings{params.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			ID:        jti,
+		},
+		Generation: subject.Generation,
+		SessionID:  subject.SessionID,
+		Scope:      scope,
+		ClientID:   client.ClientID,
+		AuthTime:   subject.AuthTime,
+		AMR:        subject.AMR,
+		ACR:        subject.ACR,
+		Ext:        subject.Ext,
+		Actor:      &token.Actor{Subject: client.ClientID, Actor: subject.Actor},
+	}
+	if slices.Contains(strings.Fields(scope), ScopePhone) {
+		claims.Phone = subject.Phone
+	}
+
+	accessToken, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCTokenResponse{
+		AccessToken:     accessToken,
+		IssuedTokenType: TokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int(expiresAt.Sub(now).Seconds()),
+		Scope:           scope,
+	}, nil
+}
+
+// downscope returns the requested scopes, or the subject's when none are
+// requested. ok is false when requested names a scope the subject lacks.
+func downscope(subjectScope, requested string) (string, bool) {
+	if requested == "" {
+		return subjectScope, true
+	}
+	granted := strings.Fields(subjectScope)
+	for _, scope := range strings.Fields(requested) {
+		if !slices.Contains(granted, scope) {
+			return "", false
+		}
+	}
+	return strings.Join(strings.Fields(requested), " "), true
+}
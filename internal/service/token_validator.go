@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/repository"
+	"otp-auth-service/internal/token"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TokenValidator checks access tokens the way every protected endpoint
+// does: signature, registered claims, all forms of revocation and the
+// user's account status.
+type TokenValidator interface {
+	ValidateAccessToken(raw string) (*token.Claims, error)
+	ValidateExchangedToken(raw string) (*token.Claims, error)
+	ValidateAPIKey(apiKey string) (*token.Claims, error)
+}
+
+type tokenValidator struct {
+	tokenRepo     repository.TokenRepository
+	userRepo      repository.UserRepository
+	clientService OAuthClientService
+	jwtCfg        config.JWT
+	exchangeCfg   config.TokenExchange
+	keys          *token.KeySet
+}
+
+func NewTokenValidator(tokenRepo repository.TokenRepository, userRepo repository.UserRepository, clientService OAuthClientService, jwtCfg config.JWT, exchangeCfg config.TokenExchange, keys *token.KeySet) TokenValidator {
+	return &tokenValidator{
+		tokenRepo:     tokenRepo,
+		userRepo:      userRepo,
+		clientService: clientService,
+		jwtCfg:        jwtCfg,
+		exchangeCfg:   exchangeCfg,
+		keys:          keys,
+	}
+}
+
+// ValidateAccessToken returns ErrInvalidToken for tokens that are malformed,
+// badly signed or outside their validity window, and ErrTokenRevoked for
+// tokens revoked by logout, session or client revocation or a generation
+// bump. Tokens of deleted, suspended or banned users are revoked too; the
+// error then also matches ErrAccountDeleted, ErrAccountSuspended or
+// ErrAccountBanned.
+func (v *tokenValidator) ValidateAccessToken(raw string) (*token.Claims, error) {
+	return v.validate(raw, []string{v.jwtCfg.Audience})
+}
+
+// ValidateExchangedToken is ValidateAccessToken for tokens minted by token
+// exchange, which are addressed to one of the exchange audiences instead of
+// this service.
+func (v *tokenValidator) ValidateExchangedToken(raw string) (*token.Claims, error) {
+	if len(v.exchangeCfg.Audiences) == 0 {
+		return nil, ErrInvalidToken
+	}
+	return v.validate(raw, v.exchangeCfg.Audiences)
+}
+
+func (v *tokenValidator) validate(raw string, audiences []string) (*token.Claims, error) {
+	claims, err := v.keys.Parse(raw)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	now := time.Now()
+	valid := false
+	for _, audience := range audiences {
+		if claims.Validate(now, v.jwtCfg.Issuer, audience, v.jwtCfg.Leeway) == nil {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, ErrInvalidToken
+	}
+
+	// Reject tokens of revoked clients
+	if claims.ClientID != "" {
+		denied, err := v.tokenRepo.IsClientDenied(claims.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if denied {
+			return nil, ErrTokenRevoked
+		}
+	}
+	if claims.IsService() {
+		return v.checkJTI(claims)
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Reject tokens issued before the user's tokens were invalidated
+	currentGeneration, err := v.tokenRepo.GetGeneration(userID)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Generation < currentGeneration {
+		return nil, ErrTokenRevoked
+	}
+
+	if _, err := v.checkJTI(claims); err != nil {
+		return nil, err
+	}
+
+	// Reject tokens whose session was signed out
+	if claims.SessionID != "" {
+		denied, err := v.tokenRepo.IsSessionDenied(claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if denied {
+			return nil, ErrTokenRevoked
+		}
+	}
+
+	// Status changes revoke tokens too, but must hold even if that failed
+	user, err := v.userRepo.FindByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTokenRevoked
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccountStatus(user); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTokenRevoked, err)
+	}
+
+	return claims, nil
+}
+
+// ValidateAPIKey authenticates a service client by API key and describes it
+// with the same claims a client_credentials token would carry.
+func (v *tokenValidator) ValidateAPIKey(apiKey string) (*token.Claims, error) {
+	client, err := v.clientService.AuthenticateAPIKey(apiKey)
+	if errors.Is(err, ErrInvalidClient) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	scope, _ := grantedServiceScopes(client, "")
+	return token.NewServiceClaims(client.ClientID, scope, v.jwtCfg.Issuer, v.jwtCfg.Audience, "", time.Now().UTC(), 0), nil
+}
+
+// checkJTI rejects tokens that were logged out.
+func (v *tokenValidator) checkJTI(claims *token.Claims) (*token.Claims, error) {
+	denied, err := v.tokenRepo.IsJTIDenied(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if denied {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
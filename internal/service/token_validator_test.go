@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/repository"
+	"otp-auth-service/internal/token"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// validatorTokenRepo revokes nothing, as when revocation failed.
+type validatorTokenRepo struct{ repository.TokenRepository }
+
+func (validatorTokenRepo) GetGeneration(uint) (int64, error)    { return 0, nil }
+func (validatorTokenRepo) IsJTIDenied(string) (bool, error)     { return false, nil }
+func (validatorTokenRepo) IsSessionDenied(string) (bool, error) { return false, nil }
+
+type validatorUserRepo struct {
+	repository.UserRepository
+	users map[uint]*model.User
+}
+
+func (r *validatorUserRepo) FindByID(id uint) (*model.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func TestValidateAccessTokenChecksAccountStatus(t *testing.T) {
+	keys, err := token.NewHMACKeySet(strings.Repeat("k", token.MinHMACSecretLength))
+	if err != nil {
+		t.Fatalf("NewHMACKeySet: %v", err)
+	}
+	jwtCfg := config.JWT{Issuer: "issuer", Audience: "audience"}
+
+	now := time.Now().UTC()
+	past := now.Add(-time.Hour)
+	users := &validatorUserRepo{users: map[uint]*model.User{
+		1: {ID: 1, Status: model.UserStatusActive},
+		2: {ID: 2, Status: model.UserStatusSuspended},
+		3: {ID: 3, Status: model.UserStatusBanned},
+		4: {ID: 4, Status: model.UserStatusSuspended, SuspendedUntil: &past},
+	}}
+	validator := NewTokenValidator(validatorTokenRepo{}, users, nil, jwtCfg, config.TokenExchange{}, keys)
+
+	tests := []struct {
+		name    string
+		userID  uint
+		wantErr error
+	}{
+		{"active", 1, nil},
+		{"suspended", 2, ErrAccountSuspended},
+		{"banned", 3, ErrAccountBanned},
+		{"suspension over", 4, nil},
+		{"deleted", 5, ErrTokenRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := token.NewClaims(tt.userID, "+15550100", jwtCfg.Issuer, jwtCfg.Audience, "jti", 0, now, time.Minute)
+			raw, err := keys.Sign(claims)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+
+			_, err = validator.ValidateAccessToken(raw)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateAccessToken = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && !errors.Is(err, ErrTokenRevoked) {
+				t.Errorf("ValidateAccessToken = %v, want it to match ErrTokenRevoked", err)
+			}
+		})
+	}
+}
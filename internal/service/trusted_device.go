@@ -0,0 +1,146 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/repository"
+	"otp-auth-service/internal/token"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TrustedDeviceService interface {
+	ListDevices(userID uint) ([]model.TrustedDeviceResponse, error)
+	RevokeDevice(userID uint, deviceID string) error
+	RevokeAllDevices(userID uint) error
+}
+
+type trustedDeviceService struct {
+	deviceRepo repository.TrustedDeviceRepository
+}
+
+func NewTrustedDeviceService(deviceRepo repository.TrustedDeviceRepository) TrustedDeviceService {
+	return &trustedDeviceService{deviceRepo: deviceRepo}
+}
+
+func (s *trustedDeviceService) ListDevices(userID uint) ([]model.TrustedDeviceResponse, error) {
+	devices, err := s.deviceRepo.ListActive(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]model.TrustedDeviceResponse, 0, len(devices))
+	for _, device := range devices {
+		responses = append(responses, model.TrustedDeviceResponse{
+			ID:         device.ID,
+			Name:       device.Name,
+			UserAgent:  device.UserAgent,
+			CreatedAt:  device.CreatedAt,
+			LastUsedAt: device.LastUsedAt,
+			ExpiresAt:  device.ExpiresAt,
+		})
+	}
+	return responses, nil
+}
+
+// RevokeDevice forgets a trusted device, so its next login needs an OTP
+// again. Sessions already started from it are left alone.
+func (s *trustedDeviceService) RevokeDevice(userID uint, deviceID string) error {
+	err := s.deviceRepo.Revoke(userID, deviceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrDeviceNotFound
+	}
+	return err
+}
+
+func (s *trustedDeviceService) RevokeAllDevices(userID uint) error {
+	return s.deviceRepo.RevokeAllForUser(userID)
+}
+
+// trustDevice registers the client as a trusted device of user and returns
+// its signed device token. Only the token's hash is stored.
+func (s *authService) trustDevice(user *model.User, client model.SessionInfo) (string, error) {
+	deviceID, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	deviceToken, err := s.keys.Sign(token.NewDeviceClaims(user.ID, s.jwtCfg.Issuer, deviceID, now, s.deviceCfg.TrustTTL))
+	if err != nil {
+		return "", err
+	}
+
+	err = s.deviceRepo.Create(&model.TrustedDevice{
+		ID:        deviceID,
+		UserID:    user.ID,
+		Name:      truncate(client.DeviceName, maxDeviceNameLength),
+		TokenHash: hashToken(deviceToken),
+		UserAgent: truncate(client.UserAgent, maxUserAgentLength),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.deviceCfg.TrustTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return deviceToken, nil
+}
+
+// DeviceLogin starts a session without an OTP for a phone number whose user
+// trusted the device holding deviceToken. Blocked numbers are refused like
+// in RequestOTP.
+func (s *authService) DeviceLogin(rawPhoneNumber, deviceToken string, client model.SessionInfo) (*model.TokenPair, error) {
+	if s.deviceCfg.TrustTTL <= 0 {
+		return nil, ErrInvalidDeviceToken
+	}
+
+	number, err := s.phoneParser.Parse(rawPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.keys.ParseDevice(deviceToken)
+	if err != nil {
+		return nil, ErrInvalidDeviceToken
+	}
+	if err := claims.Validate(time.Now().UTC(), s.jwtCfg.Issuer, s.jwtCfg.Leeway); err != nil {
+		return nil, ErrInvalidDeviceToken
+	}
+
+	device, err := s.deviceRepo.FindActive(claims.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidDeviceToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(device.TokenHash), []byte(hashToken(deviceToken))) != 1 {
+		return nil, ErrInvalidDeviceToken
+	}
+
+	// The device is trusted for one user, not for whoever holds its number
+	// now
+	user, err := s.userRepo.FindByPhoneNumber(number.E164)
+	if err != nil || user.ID != device.UserID {
+		return nil, ErrInvalidDeviceToken
+	}
+	if err := checkAccountStatus(user); err != nil {
+		return nil, err
+	}
+
+	rules, err := s.phoneRuleRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	if rule := matchPhoneRule(rules, number.E164); rule != nil && rule.List == model.PhoneListDeny {
+		return nil, ErrPhoneBlocked
+	}
+
+	if err := s.deviceRepo.TouchLastUsed(device.ID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	return s.startSession(user, client, deviceAuthentication)
+}
@@ -1,14 +1,43 @@
 package service
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
 	"otp-auth-service/internal/model"
 	"otp-auth-service/internal/repository"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+// Profile limits, matching the column sizes of the users table.
+const (
+	maxDisplayNameLength     = 100
+	maxEmailLength           = 254
+	maxAvatarURLLength       = 2048
+	maxProfileMetadataLength = 4096
 )
 
+// ProfileUpdate lists the profile fields to change. Nil fields are left
+// alone and empty strings clear a field.
+type ProfileUpdate struct {
+	DisplayName *string
+	Email       *string
+	Locale      *string
+	Timezone    *string
+	AvatarURL   *string
+	Metadata    map[string]interface{}
+}
+
 type UserService interface {
 	GetUser(id uint) (*model.UserResponse, error)
 	GetUsers(offset, limit int, search string) ([]model.UserResponse, int64, error)
 	GetMe(userID uint) (*model.UserResponse, error)
+	UpdateProfile(userID uint, update ProfileUpdate) (*model.UserResponse, error)
 }
 
 type userService struct {
@@ -25,11 +54,8 @@ func (s *userService) GetUser(id uint) (*model.UserResponse, error) {
 		return nil, err
 	}
 
-	return &model.UserResponse{
-		ID:          user.ID,
-		PhoneNumber: user.PhoneNumber,
-		CreatedAt:   user.CreatedAt,
-	}, nil
+	response := user.Response()
+	return &response, nil
 }
 
 func (s *userService) GetUsers(offset, limit int, search string) ([]model.UserResponse, int64, error) {
@@ -40,25 +66,89 @@ func (s *userService) GetUsers(offset, limit int, search string) ([]model.UserRe
 
 	var userResponses []model.UserResponse
 	for _, user := range users {
-		userResponses = append(userResponses, model.UserResponse{
-			ID:          user.ID,
-			PhoneNumber: user.PhoneNumber,
-			CreatedAt:   user.CreatedAt,
-		})
+		userResponses = append(userResponses, user.Response())
 	}
 
 	return userResponses, total, nil
 }
 
 func (s *userService) GetMe(userID uint) (*model.UserResponse, error) {
-	user, err := s.userRepo.FindByID(userID)
-	if err != nil {
-		return nil, err
+	return s.GetUser(userID)
+}
+
+// UpdateProfile validates and normalizes the changed fields and returns the
+// updated user. Invalid fields are reported as ErrInvalidProfile.
+func (s *userService) UpdateProfile(userID uint, update ProfileUpdate) (*model.UserResponse, error) {
+	fields := map[string]interface{}{}
+
+	if update.DisplayName != nil {
+		name := strings.TrimSpace(*update.DisplayName)
+		if utf8.RuneCountInString(name) > maxDisplayNameLength {
+			return nil, fmt.Errorf("%w: display_name is longer than %d characters", ErrInvalidProfile, maxDisplayNameLength)
+		}
+		fields["display_name"] = name
 	}
 
-	return &model.UserResponse{
-		ID:          user.ID,
-		PhoneNumber: user.PhoneNumber,
-		CreatedAt:   user.CreatedAt,
-	}, nil
+	if update.Email != nil {
+		email := strings.TrimSpace(*update.Email)
+		if email != "" {
+			address, err := mail.ParseAddress(email)
+			if err != nil || address.Address != email || len(email) > maxEmailLength {
+				return nil, fmt.Errorf("%w: email is not a valid address", ErrInvalidProfile)
+			}
+		}
+		fields["email"] = email
+	}
+
+	if update.Locale != nil {
+		locale := strings.TrimSpace(*update.Locale)
+		if locale != "" {
+			tag, err := language.Parse(locale)
+			if err != nil {
+				return nil, fmt.Errorf("%w: locale is not a BCP 47 language tag", ErrInvalidProfile)
+			}
+			locale = tag.String()
+		}
+		fields["locale"] = locale
+	}
+
+	if update.Timezone != nil {
+		timezone := strings.TrimSpace(*update.Timezone)
+		if timezone != "" {
+			// LoadLocation also accepts "Local", which means nothing to clients
+			if _, err := time.LoadLocation(timezone); err != nil || timezone == "Local" {
+				return nil, fmt.Errorf("%w: timezone is not an IANA time zone", ErrInvalidProfile)
+			}
+		}
+		fields["timezone"] = timezone
+	}
+
+	if update.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*update.AvatarURL)
+		if avatarURL != "" {
+			parsed, err := url.Parse(avatarURL)
+			if err != nil || parsed.Scheme != "https" || parsed.Host == "" || len(avatarURL) > maxAvatarURLLength {
+				return nil, fmt.Errorf("%w: avatar_url must be an https URL", ErrInvalidProfile)
+			}
+		}
+		fields["avatar_url"] = avatarURL
+	}
+
+	if update.Metadata != nil {
+		encoded, err := json.Marshal(update.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		if len(encoded) > maxProfileMetadataLength {
+			return nil, fmt.Errorf("%w: metadata is larger than %d bytes", ErrInvalidProfile, maxProfileMetadataLength)
+		}
+		fields["profile_metadata"] = string(encoded)
+	}
+
+	if len(fields) > 0 {
+		if err := s.userRepo.UpdateProfile(userID, fields); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetUser(userID)
 }
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"otp-auth-service/internal/model"
+	"otp-auth-service/internal/repository"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	auditActionRevokeTokens = "user.revoke_tokens"
+	auditActionSetRoles     = "user.set_roles"
+	auditActionSetMetadata  = "user.set_metadata"
+	auditActionRestore      = "user.restore"
+	auditActionSetStatus    = "user.set_status"
+)
+
+type UserAdminService interface {
+	RevokeAllTokens(userID uint, actor, reason string) error
+	SetRoles(userID uint, roles []string, actor, reason string) error
+	SetMetadata(userID uint, metadata map[string]interface{}, actor, reason string) error
+	Restore(userID uint, actor, reason string) error
+	SetStatus(userID uint, status string, suspendedUntil *time.Time, actor, reason string) error
+}
+
+type userAdminService struct {
+	userRepo    repository.UserRepository
+	tokenRepo   repository.TokenRepository
+	refreshRepo repository.RefreshTokenRepository
+	sessionRepo repository.SessionRepository
+	deviceRepo  repository.TrustedDeviceRepository
+	auditRepo   repository.AuditRepository
+}
+
+func NewUserAdminService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, refreshRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository, deviceRepo repository.TrustedDeviceRepository, auditRepo repository.AuditRepository) UserAdminService {
+	return &userAdminService{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		refreshRepo: refreshRepo,
+		sessionRepo: sessionRepo,
+		deviceRepo:  deviceRepo,
+		auditRepo:   auditRepo,
+	}
+}
+
+// RevokeAllTokens bumps the user's token generation, which invalidates every
+// access token issued so far, and revokes all of their sessions, refresh
+// tokens and trusted devices.
+func (s *userAdminService) RevokeAllTokens(userID uint, actor, reason string) error {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return err
+	}
+
+	if err := revokeAllTokens(s.tokenRepo, s.refreshRepo, s.sessionRepo, s.deviceRepo, userID); err != nil {
+		return err
+	}
+
+	return s.auditRepo.Record(&model.AuditLog{
+		Actor:     actor,
+		Action:    auditActionRevokeTokens,
+		Target:    userTarget(userID),
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// SetRoles replaces the user's roles. Access tokens issued before the change
+// stop working so no token keeps a revoked role; refresh tokens stay valid
+// and pick up the new roles.
+func (s *userAdminService) SetRoles(userID uint, roles []string, actor, reason string) error {
+	var normalized []string
+	for _, role := range roles {
+		role = strings.ToLower(strings.TrimSpace(role))
+		if !validRole(role) {
+			return fmt.Errorf("%w: %q", ErrUnknownRole, role)
+		}
+		if !slices.Contains(normalized, role) {
+			normalized = append(normalized, role)
+		}
+	}
+
+	if err := s.userRepo.UpdateRoles(userID, normalized); err != nil {
+		return err
+	}
+	if _, err := s.tokenRepo.IncrementGeneration(userID); err != nil {
+		return err
+	}
+
+	return s.auditRepo.Record(&model.AuditLog{
+		Actor:     actor,
+		Action:    auditActionSetRoles,
+		Target:    userTarget(userID),
+		Reason:    fmt.Sprintf("roles=[%s]: %s", strings.Join(normalized, " "), reason),
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// SetMetadata replaces the user's metadata. Tokens pick up the change when
+// they are next issued, at the latest on the next refresh.
+func (s *userAdminService) SetMetadata(userID uint, metadata map[string]interface{}, actor, reason string) error {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdateMetadata(userID, string(encoded)); err != nil {
+		return err
+	}
+
+	return s.auditRepo.Record(&model.AuditLog{
+		Actor:     actor,
+		Action:    auditActionSetMetadata,
+		Target:    userTarget(userID),
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// SetStatus changes the user's account status. Suspending or banning a user
+// revokes all of their tokens, sessions and trusted devices, so they are
+// signed out at once and cannot log in until reactivated. suspendedUntil
+// only applies to suspensions and must lie in the future.
+func (s *userAdminService) SetStatus(userID uint, status string, suspendedUntil *time.Time, actor, reason string) error {
+	now := time.Now().UTC()
+	switch status {
+	case model.UserStatusActive, model.UserStatusBanned:
+		if suspendedUntil != nil {
+			return fmt.Errorf("%w: only suspensions can have an end", ErrInvalidStatus)
+		}
+	case model.UserStatusSuspended:
+		if suspendedUntil != nil && !suspendedUntil.After(now) {
+			return fmt.Errorf("%w: suspended_until must be in the future", ErrInvalidStatus)
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidStatus, status)
+	}
+
+	// Revoke first so a failure never leaves a blocked user signed in
+	if status != model.UserStatusActive {
+		if err := revokeAllTokens(s.tokenRepo, s.refreshRepo, s.sessionRepo, s.deviceRepo, userID); err != nil {
+			return err
+		}
+	}
+	if err := s.userRepo.UpdateStatus(userID, status, reason, suspendedUntil, now); err != nil {
+		return err
+	}
+
+	detail := "status=" + status
+	if suspendedUntil != nil {
+		detail += " until=" + suspendedUntil.UTC().Format(time.RFC3339)
+	}
+	return s.auditRepo.Record(&model.AuditLog{
+		Actor:     actor,
+		Action:    auditActionSetStatus,
+		Target:    userTarget(userID),
+		Reason:    detail + ": " + reason,
+		CreatedAt: now,
+	})
+}
+
+// Restore cancels the deletion of an account that has not been purged yet.
+// The user logs in again with an OTP; tokens revoked by the deletion stay
+// revoked.
+func (s *userAdminService) Restore(userID uint, actor, reason string) error {
+	if err := s.userRepo.Restore(userID); err != nil {
+		return err
+	}
+
+	return s.auditRepo.Record(&model.AuditLog{
+		Actor:     actor,
+		Action:    auditActionRestore,
+		Target:    userTarget(userID),
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// userTarget formats a user ID as an audit log target.
+func userTarget(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
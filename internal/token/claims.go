@@ -0,0 +1,222 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrTokenExpired     = errors.New("token is expired")
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+	ErrInvalidIssuer    = errors.New("token has an unexpected issuer")
+	ErrInvalidAudience  = errors.New("token has an unexpected audience")
+	ErrMissingClaim     = errors.New("token is missing a required claim")
+)
+
+// Claims are the claims of an access token. The subject is the user ID.
+type Claims struct {
+	jwt.RegisteredClaims
+	Phone string `json:"phone"`
+	// Generation is the user's token generation at issue time; tokens from
+	// an older generation have been revoked.
+	Generation int64 `json:"gen"`
+	// SessionID ties the token to the login session it was issued for.
+	SessionID string `json:"sid,omitempty"`
+	// Roles are the user's roles; first-party tokens also carry the scopes
+	// they grant. Tokens issued to OAuth clients carry the granted scope and
+	// the client ID instead of roles.
+	Roles    []string `json:"roles,omitempty"`
+	Scope    string   `json:"scope,omitempty"`
+	ClientID string   `json:"client_id,omitempty"`
+	// AuthTime, AMR and ACR describe when and how the user last proved who
+	// they are in the token's session.
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	AMR      []string         `json:"amr,omitempty"`
+	ACR      string           `json:"acr,omitempty"`
+	// Actor names the party a token exchange issued the token to, acting
+	// for the subject (RFC 8693).
+	Actor *Actor `json:"act,omitempty"`
+	// Ext holds custom claims added by claims enrichers.
+	Ext map[string]interface{} `json:"ext,omitempty"`
+	// Confirmation is set on DPoP-bound tokens, which are only accepted
+	// with a proof signed by the confirmed key.
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+}
+
+// Actor is an act claim. A nested Actor records earlier delegations, most
+// recent first.
+type Actor struct {
+	Subject string `json:"sub"`
+	Actor   *Actor `json:"act,omitempty"`
+}
+
+// IDTokenClaims are the claims of an OpenID Connect ID token. The audience
+// is the client the token was issued to.
+type IDTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce               string   `json:"nonce,omitempty"`
+	AuthTime            int64    `json:"auth_time"`
+	AMR                 []string `json:"amr,omitempty"`
+	ACR                 string   `json:"acr,omitempty"`
+	SessionID           string   `json:"sid,omitempty"`
+	PhoneNumber         string   `json:"phone_number,omitempty"`
+	PhoneNumberVerified bool     `json:"phone_number_verified,omitempty"`
+}
+
+// NewClaims builds access token claims for a user, valid from now for ttl.
+func NewClaims(userID uint, phone, issuer, audience, jti string, generation int64, now time.Time, ttl time.Duration) *Claims {
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			ID:        jti,
+		},
+		Phone:      phone,
+		Generation: generation,
+	}
+}
+
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(strings.Fields(c.Scope), scope)
+}
+
+// NewServiceClaims builds claims for a token issued to a service client
+// through the client_credentials grant. The client is its own subject.
+func NewServiceClaims(clientID, scope, issuer, audience, jti string, now time.Time, ttl time.Duration) *Claims {
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   clientID,
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			ID:        jti,
+		},
+		Scope:    scope,
+		ClientID: clientID,
+	}
+}
+
+// IsService reports whether the token was issued to a service client
+// rather than on behalf of a user.
+func (c *Claims) IsService() bool {
+	return c.ClientID != "" && c.Subject == c.ClientID
+}
+
+// UserID parses the subject as a user ID.
+func (c *Claims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: sub", ErrMissingClaim)
+	}
+	return uint(id), nil
+}
+
+// Validate checks the registered claims. exp, iat and sub are required;
+// leeway absorbs clock skew between issuer and verifier.
+func (c *Claims) Validate(now time.Time, issuer, audience string, leeway time.Duration) error {
+	return validateRegistered(&c.RegisteredClaims, now, issuer, audience, leeway)
+}
+
+// DeviceClaims are the claims of a trusted device token. The subject is the
+// user ID and the token ID is the device ID.
+type DeviceClaims struct {
+	jwt.RegisteredClaims
+}
+
+// DeviceAudience is the audience of device tokens. It keeps them from being
+// accepted as access tokens.
+const DeviceAudience = "trusted-device"
+
+// NewDeviceClaims builds claims for a device token, valid from now for ttl.
+func NewDeviceClaims(userID uint, issuer, deviceID string, now time.Time, ttl time.Duration) *DeviceClaims {
+	return &DeviceClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{DeviceAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			ID:        deviceID,
+		},
+	}
+}
+
+// Validate checks the registered claims like Claims.Validate, against
+// DeviceAudience.
+func (c *DeviceClaims) Validate(now time.Time, issuer string, leeway time.Duration) error {
+	return validateRegistered(&c.RegisteredClaims, now, issuer, DeviceAudience, leeway)
+}
+
+func validateRegistered(c *jwt.RegisteredClaims, now time.Time, issuer, audience string, leeway time.Duration) error {
+	if c.Subject == "" {
+		return fmt.Errorf("%w: sub", ErrMissingClaim)
+	}
+	if c.ID == "" {
+		return fmt.Errorf("%w: jti", ErrMissingClaim)
+	}
+	if c.ExpiresAt == nil {
+		return fmt.Errorf("%w: exp", ErrMissingClaim)
+	}
+	if c.IssuedAt == nil {
+		return fmt.Errorf("%w: iat", ErrMissingClaim)
+	}
+	if !now.Add(-leeway).Before(c.ExpiresAt.Time) {
+		return ErrTokenExpired
+	}
+	if c.NotBefore != nil && now.Add(leeway).Before(c.NotBefore.Time) {
+		return ErrTokenNotYetValid
+	}
+	if now.Add(leeway).Before(c.IssuedAt.Time) {
+		return ErrTokenNotYetValid
+	}
+	if c.Issuer != issuer {
+		return ErrInvalidIssuer
+	}
+	if !c.VerifyAudience(audience, true) {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+// Parse verifies the signature of tokenString with the key set and decodes
+// its claims. Registered claims are checked separately by Validate so the
+// caller controls leeway.
+func (s *KeySet) Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	if err := s.parse(tokenString, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ParseDevice is Parse for device tokens.
+func (s *KeySet) ParseDevice(tokenString string) (*DeviceClaims, error) {
+	claims := &DeviceClaims{}
+	if err := s.parse(tokenString, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (s *KeySet) parse(tokenString string, claims jwt.Claims) error {
+	parser := jwt.NewParser(jwt.WithValidMethods(s.ValidMethods()), jwt.WithoutClaimsValidation())
+	_, err := parser.ParseWithClaims(tokenString, claims, s.Keyfunc)
+	return err
+}
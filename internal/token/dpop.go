@@ -0,0 +1,193 @@
+package token
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// DPoPProofType is the typ header of DPoP proofs (RFC 9449).
+const DPoPProofType = "dpop+jwt"
+
+var ErrInvalidProof = errors.New("invalid DPoP proof")
+
+// DPoPAlgorithms are the proof signing algorithms accepted. Symmetric
+// algorithms are never accepted since the key travels in the proof.
+var DPoPAlgorithms = []string{"ES256", "ES384", "RS256", "PS256", "EdDSA"}
+
+// minDPoPRSABits is the smallest RSA modulus accepted for proof keys.
+const minDPoPRSABits = 2048
+
+// Confirmation binds a token to a key, RFC 7800. JKT is the RFC 7638
+// thumbprint of the client's DPoP key.
+type Confirmation struct {
+	JKT string `json:"jkt"`
+}
+
+// DPoPClaims are the claims of a DPoP proof. The jti and iat are required.
+type DPoPClaims struct {
+	jwt.RegisteredClaims
+	Method string `json:"htm"`
+	URL    string `json:"htu"`
+	// AccessTokenHash is set on proofs sent with an access token.
+	AccessTokenHash string `json:"ath,omitempty"`
+	Nonce           string `json:"nonce,omitempty"`
+}
+
+// DPoPProof is a proof whose signature has been checked against the key in
+// its jwk header.
+type DPoPProof struct {
+	Claims DPoPClaims
+	// Thumbprint identifies the proof key; tokens bound to the key carry it
+	// as cnf.jkt.
+	Thumbprint string
+}
+
+// ParseDPoPProof verifies that proof is a DPoP proof signed by the public
+// key in its header and returns its claims, which are left to the caller to
+// check.
+func ParseDPoPProof(proof string) (*DPoPProof, error) {
+	result := &DPoPProof{}
+	parser := jwt.NewParser(jwt.WithValidMethods(DPoPAlgorithms), jwt.WithoutClaimsValidation())
+	_, err := parser.ParseWithClaims(proof, &result.Claims, func(t *jwt.Token) (interface{}, error) {
+		if typ, _ := t.Header["typ"].(string); typ != DPoPProofType {
+			return nil, errors.New("unexpected typ")
+		}
+		header, ok := t.Header["jwk"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("missing jwk")
+		}
+		if _, private := header["d"]; private {
+			return nil, errors.New("jwk contains a private key")
+		}
+
+		key, thumbprint, err := parsePublicJWK(header)
+		if err != nil {
+			return nil, err
+		}
+		result.Thumbprint = thumbprint
+		return key, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+	return result, nil
+}
+
+// AccessTokenHash is the ath claim of proofs sent with accessToken.
+func AccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return encode(sum[:])
+}
+
+// parsePublicJWK decodes a public JWK and computes its RFC 7638 thumbprint
+// from the required members in lexicographic order.
+func parsePublicJWK(header map[string]interface{}) (interface{}, string, error) {
+	raw, err := json.Marshal(header)
+	if err != nil {
+		return nil, "", err
+	}
+	var jwk JWK
+	if err := json.Unmarshal(raw, &jwk); err != nil {
+		return nil, "", err
+	}
+
+	var key interface{}
+	var members string
+	switch jwk.KeyType {
+	case "EC":
+		key, err = ecPublicKey(jwk)
+		members = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, jwk.Curve, jwk.X, jwk.Y)
+	case "RSA":
+		key, err = rsaPublicKey(jwk)
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case "OKP":
+		key, err = ed25519PublicKey(jwk)
+		members = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, jwk.Curve, jwk.X)
+	default:
+		err = fmt.Errorf("unsupported kty %q", jwk.KeyType)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum := sha256.Sum256([]byte(members))
+	return key, encode(sum[:]), nil
+}
+
+func ecPublicKey(jwk JWK) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	var checker ecdh.Curve
+	switch jwk.Curve {
+	case "P-256":
+		curve, checker = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, checker = elliptic.P384(), ecdh.P384()
+	default:
+		return nil, fmt.Errorf("unsupported crv %q", jwk.Curve)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, err
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x) != size || len(y) != size {
+		return nil, errors.New("invalid EC coordinates")
+	}
+
+	// Rejects points that are not on the curve
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := checker.NewPublicKey(point); err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
+
+func rsaPublicKey(jwk JWK) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	modulus := new(big.Int).SetBytes(n)
+	exponent := new(big.Int).SetBytes(e)
+	if modulus.BitLen() < minDPoPRSABits {
+		return nil, errors.New("RSA key too small")
+	}
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA exponent")
+	}
+	return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+}
+
+func ed25519PublicKey(jwk JWK) (ed25519.PublicKey, error) {
+	if jwk.Curve != "Ed25519" {
+		return nil, fmt.Errorf("unsupported crv %q", jwk.Curve)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, err
+	}
+	if len(x) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid Ed25519 key")
+	}
+	return ed25519.PublicKey(x), nil
+}
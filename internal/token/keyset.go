@@ -0,0 +1,161 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrNoSigningKey = errors.New("no active signing key")
+	ErrUnknownKey   = errors.New("unknown signing key")
+)
+
+type KeyState string
+
+const (
+	// StatePending keys are published but not used yet.
+	StatePending KeyState = "pending"
+	// StateActive keys sign new tokens and verify existing ones.
+	StateActive KeyState = "active"
+	// StateVerifyOnly keys no longer sign but still verify tokens they signed.
+	StateVerifyOnly KeyState = "verify_only"
+	// StateRetired keys are rejected.
+	StateRetired KeyState = "retired"
+)
+
+// Key is one signing key on the rotation schedule. A zero ActiveUntil keeps
+// the key active indefinitely; a zero RetireAt keeps it verifiable forever.
+type Key struct {
+	ID          string
+	Method      jwt.SigningMethod
+	ActiveFrom  time.Time
+	ActiveUntil time.Time
+	RetireAt    time.Time
+
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func (k *Key) State(now time.Time) KeyState {
+	switch {
+	case !k.RetireAt.IsZero() && !now.Before(k.RetireAt):
+		return StateRetired
+	case now.Before(k.ActiveFrom):
+		return StatePending
+	case !k.ActiveUntil.IsZero() && !now.Before(k.ActiveUntil):
+		return StateVerifyOnly
+	}
+	return StateActive
+}
+
+// KeySet signs tokens with the newest active key and verifies them with
+// whichever key their kid header names.
+type KeySet struct {
+	keys []*Key
+	now  func() time.Time
+}
+
+func NewKeySet(keys []*Key) (*KeySet, error) {
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("signing key without kid")
+		}
+		if seen[key.ID] {
+			return nil, fmt.Errorf("duplicate signing key %q", key.ID)
+		}
+		seen[key.ID] = true
+	}
+
+	sorted := append([]*Key(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActiveFrom.After(sorted[j].ActiveFrom)
+	})
+
+	return &KeySet{keys: sorted, now: time.Now}, nil
+}
+
+// SigningKey returns the active key that became active most recently.
+func (s *KeySet) SigningKey() (*Key, error) {
+	now := s.now()
+	for _, key := range s.keys {
+		if key.State(now) == StateActive {
+			return key, nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+// Sign signs claims with the current signing key and sets the kid header.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	key, err := s.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
+}
+
+// Keyfunc selects the verification key by kid and rejects tokens whose alg
+// differs from the key's algorithm. Tokens without a kid are accepted only
+// while a single key is verifiable, which keeps tokens issued before key
+// IDs were introduced working.
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	now := s.now()
+
+	var key *Key
+	if kid, ok := token.Header["kid"].(string); ok {
+		for _, candidate := range s.keys {
+			if candidate.ID == kid {
+				key = candidate
+				break
+			}
+		}
+	} else {
+		verifiable := s.verifiable(now)
+		if len(verifiable) == 1 {
+			key = verifiable[0]
+		}
+	}
+
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+	if state := key.State(now); state != StateActive && state != StateVerifyOnly {
+		return nil, fmt.Errorf("signing key %q is %s", key.ID, state)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), key.ID)
+	}
+	return key.verifyKey, nil
+}
+
+// ValidMethods lists the algorithms of all keys, for jwt.WithValidMethods.
+func (s *KeySet) ValidMethods() []string {
+	var methods []string
+	seen := make(map[string]bool)
+	for _, key := range s.keys {
+		alg := key.Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+func (s *KeySet) verifiable(now time.Time) []*Key {
+	var keys []*Key
+	for _, key := range s.keys {
+		if state := key.State(now); state == StateActive || state == StateVerifyOnly {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,165 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// hmacKeyID identifies the key built from a plain shared secret.
+const hmacKeyID = "hs256"
+
+// MinHMACSecretLength is the shortest secret accepted for HS256, matching
+// the 256-bit output of its hash.
+const MinHMACSecretLength = 32
+
+type manifest struct {
+	Keys []manifestKey `json:"keys"`
+}
+
+type manifestKey struct {
+	ID             string    `json:"kid"`
+	Algorithm      string    `json:"alg"`
+	PrivateKeyFile string    `json:"private_key_file"`
+	SecretFile     string    `json:"secret_file"`
+	ActiveFrom     time.Time `json:"active_from"`
+	ActiveUntil    time.Time `json:"active_until"`
+	RetireAt       time.Time `json:"retire_at"`
+}
+
+// NewHMACKeySet returns a key set holding a single, always active HS256 key.
+func NewHMACKeySet(secret string) (*KeySet, error) {
+	if err := checkHMACSecret([]byte(secret)); err != nil {
+		return nil, err
+	}
+	return NewKeySet([]*Key{{
+		ID:        hmacKeyID,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}})
+}
+
+// LoadKeySet reads a JSON manifest describing the rotation schedule:
+//
+//	{"keys": [{"kid": "2026-01", "alg": "ES256", "private_key_file": "2026-01.pem",
+//	           "active_from": "2026-01-01T00:00:00Z", "active_until": "2026-04-01T00:00:00Z",
+//	           "retire_at": "2026-04-02T00:00:00Z"}]}
+//
+// Supported algorithms are RS256, ES256, EdDSA and HS256 (with secret_file).
+// Relative key paths are resolved against the manifest's directory.
+func LoadKeySet(manifestPath string) (*KeySet, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading key manifest: %w", err)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing key manifest: %w", err)
+	}
+	if len(m.Keys) == 0 {
+		return nil, errors.New("key manifest has no keys")
+	}
+
+	dir := filepath.Dir(manifestPath)
+	keys := make([]*Key, 0, len(m.Keys))
+	for _, entry := range m.Keys {
+		key, err := loadKey(dir, entry)
+		if err != nil {
+			return nil, fmt.Errorf("loading key %q: %w", entry.ID, err)
+		}
+		keys = append(keys, key)
+	}
+	return NewKeySet(keys)
+}
+
+func checkHMACSecret(secret []byte) error {
+	if len(secret) == 0 {
+		return errors.New("empty HMAC secret")
+	}
+	if len(secret) < MinHMACSecretLength {
+		return fmt.Errorf("HMAC secret must be at least %d bytes", MinHMACSecretLength)
+	}
+	return nil
+}
+
+func loadKey(dir string, entry manifestKey) (*Key, error) {
+	key := &Key{
+		ID:          entry.ID,
+		ActiveFrom:  entry.ActiveFrom,
+		ActiveUntil: entry.ActiveUntil,
+		RetireAt:    entry.RetireAt,
+	}
+
+	if entry.Algorithm == jwt.SigningMethodHS256.Alg() {
+		secret, err := readFile(dir, entry.SecretFile)
+		if err != nil {
+			return nil, err
+		}
+		secret = []byte(strings.TrimSpace(string(secret)))
+		if err := checkHMACSecret(secret); err != nil {
+			return nil, err
+		}
+		key.Method = jwt.SigningMethodHS256
+		key.signKey, key.verifyKey = secret, secret
+		return key, nil
+	}
+
+	pemData, err := readFile(dir, entry.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch entry.Algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, err
+		}
+		key.Method = jwt.SigningMethodRS256
+		key.signKey, key.verifyKey = private, &private.PublicKey
+	case jwt.SigningMethodES256.Alg():
+		private, err := jwt.ParseECPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, err
+		}
+		if private.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 requires a P-256 key")
+		}
+		key.Method = jwt.SigningMethodES256
+		key.signKey, key.verifyKey = private, &private.PublicKey
+	case jwt.SigningMethodEdDSA.Alg():
+		private, err := jwt.ParseEdPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, err
+		}
+		edKey, ok := private.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("EdDSA requires an Ed25519 key")
+		}
+		key.Method = jwt.SigningMethodEdDSA
+		key.signKey, key.verifyKey = edKey, edKey.Public()
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", entry.Algorithm)
+	}
+	return key, nil
+}
+
+func readFile(dir, path string) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("key file not set")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE phone_rules (
+                             id SERIAL PRIMARY KEY,
+                             list VARCHAR(10) NOT NULL CHECK (list IN ('allow', 'deny', 'test')),
+                             match_type VARCHAR(10) NOT NULL CHECK (match_type IN ('exact', 'prefix')),
+                             value VARCHAR(20) NOT NULL,
+                             note TEXT NOT NULL DEFAULT '',
+                             created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                             UNIQUE (list, match_type, value)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS phone_rules;
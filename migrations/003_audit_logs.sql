@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE audit_logs (
+                            id SERIAL PRIMARY KEY,
+                            actor VARCHAR(100) NOT NULL,
+                            action VARCHAR(100) NOT NULL,
+                            target VARCHAR(100) NOT NULL,
+                            reason TEXT NOT NULL,
+                            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_logs_target ON audit_logs(target);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs(created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_logs;
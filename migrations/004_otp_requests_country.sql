@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE otp_requests ADD COLUMN country VARCHAR(2) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_otp_requests_country ON otp_requests(country);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_otp_requests_country;
+ALTER TABLE otp_requests DROP COLUMN IF EXISTS country;
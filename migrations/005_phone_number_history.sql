@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE phone_number_history (
+                                      id SERIAL PRIMARY KEY,
+                                      user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                      old_phone_number VARCHAR(20) NOT NULL,
+                                      new_phone_number VARCHAR(20) NOT NULL,
+                                      changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_phone_number_history_user_id ON phone_number_history(user_id);
+CREATE INDEX idx_phone_number_history_old_phone_number ON phone_number_history(old_phone_number);
+
+-- +goose Down
+DROP TABLE IF EXISTS phone_number_history;
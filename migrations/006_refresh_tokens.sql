@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE refresh_tokens (
+                                id SERIAL PRIMARY KEY,
+                                user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                family_id VARCHAR(64) NOT NULL,
+                                token_hash VARCHAR(64) UNIQUE NOT NULL,
+                                expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                                used_at TIMESTAMP WITH TIME ZONE,
+                                revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS refresh_tokens;
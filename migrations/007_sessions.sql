@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE sessions (
+                          id VARCHAR(64) PRIMARY KEY,
+                          user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                          device_name VARCHAR(100),
+                          ip_address VARCHAR(45),
+                          user_agent VARCHAR(512),
+                          created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                          last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                          revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS sessions;
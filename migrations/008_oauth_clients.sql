@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE oauth_clients (
+                               id SERIAL PRIMARY KEY,
+                               client_id VARCHAR(64) UNIQUE NOT NULL,
+                               secret_hash VARCHAR(64),
+                               name VARCHAR(100) NOT NULL,
+                               redirect_uris TEXT NOT NULL,
+                               scopes TEXT NOT NULL,
+                               created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                               revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS oauth_clients;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN roles TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS roles;
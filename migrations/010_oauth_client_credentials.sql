@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE oauth_clients ADD COLUMN grant_types TEXT NOT NULL DEFAULT 'authorization_code';
+ALTER TABLE oauth_clients ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE oauth_clients ADD COLUMN last_used_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_oauth_clients_secret_hash ON oauth_clients(secret_hash);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_oauth_clients_secret_hash;
+ALTER TABLE oauth_clients DROP COLUMN IF EXISTS last_used_at;
+ALTER TABLE oauth_clients DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE oauth_clients DROP COLUMN IF EXISTS grant_types;
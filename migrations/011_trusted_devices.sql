@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE trusted_devices (
+                                 id VARCHAR(64) PRIMARY KEY,
+                                 user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                 name VARCHAR(100),
+                                 token_hash VARCHAR(64) NOT NULL,
+                                 user_agent VARCHAR(512),
+                                 created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                                 last_used_at TIMESTAMP WITH TIME ZONE,
+                                 expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                 revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_trusted_devices_user_id ON trusted_devices(user_id);
+CREATE UNIQUE INDEX idx_trusted_devices_token_hash ON trusted_devices(token_hash);
+
+-- +goose Down
+DROP TABLE IF EXISTS trusted_devices;
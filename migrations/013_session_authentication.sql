@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE sessions ADD COLUMN auth_time TIMESTAMP WITH TIME ZONE;
+ALTER TABLE sessions ADD COLUMN amr VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN acr VARCHAR(16) NOT NULL DEFAULT '';
+
+-- Every session so far started with an OTP login
+UPDATE sessions SET auth_time = created_at, amr = 'otp', acr = '2';
+
+-- +goose Down
+ALTER TABLE sessions DROP COLUMN IF EXISTS acr;
+ALTER TABLE sessions DROP COLUMN IF EXISTS amr;
+ALTER TABLE sessions DROP COLUMN IF EXISTS auth_time;
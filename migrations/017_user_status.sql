@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD COLUMN status_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN status_changed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_users_status ON users(status);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_status;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_until;
+ALTER TABLE users DROP COLUMN IF EXISTS status_changed_at;
+ALTER TABLE users DROP COLUMN IF EXISTS status_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS status;